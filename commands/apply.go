@@ -12,6 +12,9 @@ import (
 var (
 	applyNoCommit    bool
 	applyConcurrency int
+	applyAllowStale  bool
+	applyExecutor    string
+	applyStream      bool
 )
 
 var applyCmd = &cobra.Command{
@@ -20,18 +23,29 @@ var applyCmd = &cobra.Command{
 	Long: `Reads the plan from .bear/plan.yml (created by 'bear plan') and
 executes the deployments in parallel.
 
+Artifacts are deployed in dependency order: an artifact only deploys
+after everything it depends on has deployed. If a deployment fails,
+its dependents are skipped.
+
 After successful deployment, the lock file is updated and automatically
 committed with [skip ci]. Use --no-commit to disable auto-commit.
 
 The plan file is removed after execution.
 
+The plan records HEAD and fingerprints of the resolved config, the lock
+file and the presets. If any of them changed since 'bear plan', apply
+refuses to run and lists what drifted. Use --allow-stale to apply anyway.
+
 Requires a plan file — run 'bear plan' first.
 
 Examples:
   bear plan && bear apply          # Plan and apply
   bear apply                       # Apply existing plan
   bear apply --no-commit           # Apply without committing lock file
-  bear apply --concurrency 5       # Limit parallel deployments`,
+  bear apply --concurrency 5       # Limit parallel deployments
+  bear apply --allow-stale         # Apply even if the plan is outdated
+  bear apply --executor dry-run    # Print deploy commands without running them
+  bear apply --stream=false        # Only print output when a deployment fails`,
 	RunE: func(c *cobra.Command, args []string) error {
 		// Convert to absolute path
 		absDir, err := filepath.Abs(workDir)
@@ -45,13 +59,20 @@ Examples:
 		}
 
 		opts := cmd.Options{
-			Force:       force,
-			NoCommit:    applyNoCommit,
-			Concurrency: applyConcurrency,
-			Verbose:     verbose,
+			Force:          force,
+			NoCommit:       applyNoCommit,
+			Concurrency:    applyConcurrency,
+			Verbose:        verbose,
+			Environment:    env,
+			AllowStale:     applyAllowStale,
+			Executor:       applyExecutor,
+			ConfigOverlays: configOverlays,
+		}
+		if c.Flags().Changed("stream") {
+			opts.Stream = &applyStream
 		}
 
-		return cmd.ApplyWithOptions(configPath, opts)
+		return cmd.ApplyWithOptions(c.Context(), configPath, opts)
 	},
 }
 
@@ -59,4 +80,7 @@ func init() {
 	rootCmd.AddCommand(applyCmd)
 	applyCmd.Flags().BoolVar(&applyNoCommit, "no-commit", false, "Do not commit and push lock file after deployment")
 	applyCmd.Flags().IntVar(&applyConcurrency, "concurrency", 10, "Maximum number of parallel deployment jobs")
+	applyCmd.Flags().BoolVar(&applyAllowStale, "allow-stale", false, "Apply the plan even if config, lock file or HEAD changed since planning")
+	applyCmd.Flags().StringVar(&applyExecutor, "executor", "", "Step executor (shell, dry-run), overrides the config")
+	applyCmd.Flags().BoolVar(&applyStream, "stream", false, "Stream step output live (default: on with --verbose or without a terminal)")
 }
@@ -14,7 +14,7 @@ var checkCmd = &cobra.Command{
 	Short: "Validate configuration and dependencies",
 	Long: `Validates the Bear configuration and checks for issues:
 
-- Config syntax (bear.config.yml, bear.artifact.yml, bear.lib.yml)
+- Config syntax and unknown fields (bear.config.yml, bear.artifact.yml, bear.lib.yml)
 - All dependencies exist and can be resolved
 - No circular dependencies
 - All referenced targets exist
@@ -35,7 +35,7 @@ Examples:
 			return fmt.Errorf("config file not found: %s", configPath)
 		}
 
-		return cmd.Check(configPath)
+		return cmd.Check(configPath, cmd.Options{Environment: env, ConfigOverlays: configOverlays})
 	},
 }
 
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/irevolve/bear/internal/cmd"
+	"github.com/spf13/cobra"
+)
+
+var forceUnlockCmd = &cobra.Command{
+	Use:   "force-unlock",
+	Short: "Remove the state lock",
+	Long: `Removes the state lock regardless of who holds it.
+
+bear plan and bear apply take an advisory state lock so that concurrent
+runs cannot overwrite each other's lock file. If a run was killed before
+it could release the lock, use this command to remove it.
+
+Only use this when you are sure that no other run is active.
+
+Examples:
+  bear force-unlock              # Unlock the default environment
+  bear force-unlock --env prod   # Unlock an environment`,
+	Args: cobra.NoArgs,
+	RunE: func(c *cobra.Command, args []string) error {
+		// Convert to absolute path
+		absDir, err := filepath.Abs(workDir)
+		if err != nil {
+			return fmt.Errorf("invalid path: %w", err)
+		}
+
+		configPath := filepath.Join(absDir, "bear.config.yml")
+		if _, err := os.Stat(configPath); os.IsNotExist(err) {
+			return fmt.Errorf("config file not found: %s", configPath)
+		}
+
+		return cmd.ForceUnlock(configPath, cmd.Options{Environment: env, ConfigOverlays: configOverlays})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(forceUnlockCmd)
+}
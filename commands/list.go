@@ -22,7 +22,11 @@ Use --tree to display as a dependency tree.
 Examples:
   bear list                # List all artifacts
   bear list --tree         # Show as dependency tree
+  bear list --tree --env prod  # Show deployed versions in prod
+  bear list --env prod     # Show deployed versions and outputs in prod
   bear list user-api       # Show specific artifact tree
+  bear list --tag team-payments  # List artifacts with a tag
+  bear list --tree --tag api+go  # Tree of artifacts with both tags
   bear list -d ./project   # List artifacts in different directory`,
 	RunE: func(c *cobra.Command, args []string) error {
 		// Convert to absolute path
@@ -36,14 +40,23 @@ Examples:
 			return fmt.Errorf("config file not found: %s", configPath)
 		}
 
+		opts := cmd.Options{
+			Artifacts:      args,
+			Tags:           selectTags,
+			ExcludeTags:    excludeTags,
+			WithDeps:       withDeps,
+			Environment:    env,
+			ConfigOverlays: configOverlays,
+		}
 		if showTree {
-			return cmd.Tree(configPath, args)
+			return cmd.Tree(configPath, opts)
 		}
-		return cmd.List(configPath)
+		return cmd.List(configPath, opts)
 	},
 }
 
 func init() {
 	listCmd.Flags().BoolVar(&showTree, "tree", false, "Display as dependency tree")
+	addSelectFlags(listCmd)
 	rootCmd.AddCommand(listCmd)
 }
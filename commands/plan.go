@@ -12,6 +12,10 @@ import (
 var (
 	planConcurrency int
 	planPinCommit   string
+	planOutput      string
+	planExecutor    string
+	planStream      bool
+	planNoCache     bool
 )
 
 var planCmd = &cobra.Command{
@@ -32,8 +36,16 @@ Examples:
   bear plan                        # Plan all changed artifacts
   bear plan user-api               # Plan specific artifact
   bear plan user-api order-api     # Plan multiple artifacts
+  bear plan --tag team-payments --exclude-tag experimental
+                                   # Plan artifacts by tag
+  bear plan user-api --with-deps   # Plan an artifact and its dependencies
   bear plan --pin abc123           # Pin artifact(s) to specific commit
   bear plan --concurrency 5        # Limit parallel validations
+  bear plan --env staging          # Plan against the staging lock state
+  bear plan --output json          # Print the plan as JSON (progress goes to stderr)
+  bear plan --executor dry-run     # Print validation commands without running them
+  bear plan --stream               # Show validation output live
+  bear plan --no-cache             # Validate again, ignoring cached results
   bear plan -d ./other-project     # Plan in different directory`,
 	RunE: func(c *cobra.Command, args []string) error {
 		// Convert to absolute path
@@ -48,19 +60,35 @@ Examples:
 		}
 
 		opts := cmd.Options{
-			Artifacts:   args,
-			PinCommit:   planPinCommit,
-			Force:       force,
-			Concurrency: planConcurrency,
-			Verbose:     verbose,
+			Artifacts:      args,
+			Tags:           selectTags,
+			ExcludeTags:    excludeTags,
+			WithDeps:       withDeps,
+			PinCommit:      planPinCommit,
+			Force:          force,
+			Concurrency:    planConcurrency,
+			Verbose:        verbose,
+			Environment:    env,
+			Output:         planOutput,
+			Executor:       planExecutor,
+			NoCache:        planNoCache,
+			ConfigOverlays: configOverlays,
+		}
+		if c.Flags().Changed("stream") {
+			opts.Stream = &planStream
 		}
 
-		return cmd.PlanWithOptions(configPath, opts)
+		return cmd.PlanWithOptions(c.Context(), configPath, opts)
 	},
 }
 
 func init() {
 	planCmd.Flags().IntVar(&planConcurrency, "concurrency", 10, "Maximum number of parallel validation jobs")
 	planCmd.Flags().StringVar(&planPinCommit, "pin", "", "Pin artifact(s) to a specific commit")
+	planCmd.Flags().StringVarP(&planOutput, "output", "o", "text", "Output format (text, json, yaml)")
+	planCmd.Flags().StringVar(&planExecutor, "executor", "", "Step executor (shell, dry-run), overrides the config")
+	planCmd.Flags().BoolVar(&planStream, "stream", false, "Stream step output live (default: on with --verbose or without a terminal)")
+	planCmd.Flags().BoolVar(&planNoCache, "no-cache", false, "Validate again instead of using cached results")
+	addSelectFlags(planCmd)
 	rootCmd.AddCommand(planCmd)
 }
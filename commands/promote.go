@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/irevolve/bear/internal/cmd"
+	"github.com/spf13/cobra"
+)
+
+var (
+	promoteFrom        string
+	promoteTo          string
+	promoteConcurrency int
+)
+
+var promoteCmd = &cobra.Command{
+	Use:   "promote --from <env> --to <env> [artifacts...]",
+	Short: "Plan deploying the versions of one environment to another",
+	Long: `Creates a deployment plan for the target environment that deploys
+exactly the commits recorded in the source environment's lock file.
+
+Artifacts that are already at the source commit, pinned in the target
+environment, or not deployed to the source environment are skipped.
+The artifacts were validated when they were planned for the source
+environment, so promote only plans deployments.
+
+Run 'bear apply' afterwards to execute the plan.
+
+Examples:
+  bear promote --from staging --to prod             # Promote everything
+  bear promote --from staging --to prod user-api    # Promote one artifact
+  bear promote --from staging --to prod && bear apply`,
+	RunE: func(c *cobra.Command, args []string) error {
+		// Convert to absolute path
+		absDir, err := filepath.Abs(workDir)
+		if err != nil {
+			return fmt.Errorf("invalid path: %w", err)
+		}
+
+		configPath := filepath.Join(absDir, "bear.config.yml")
+		if _, err := os.Stat(configPath); os.IsNotExist(err) {
+			return fmt.Errorf("config file not found: %s", configPath)
+		}
+
+		opts := cmd.Options{
+			Artifacts:      args,
+			Force:          force,
+			Concurrency:    promoteConcurrency,
+			Verbose:        verbose,
+			Environment:    promoteTo,
+			PromoteFrom:    promoteFrom,
+			ConfigOverlays: configOverlays,
+		}
+
+		return cmd.PlanWithOptions(c.Context(), configPath, opts)
+	},
+}
+
+func init() {
+	promoteCmd.Flags().StringVar(&promoteFrom, "from", "", "Source environment")
+	promoteCmd.Flags().StringVar(&promoteTo, "to", "", "Target environment")
+	promoteCmd.Flags().IntVar(&promoteConcurrency, "concurrency", 10, "Maximum number of parallel jobs")
+	promoteCmd.MarkFlagRequired("from")
+	promoteCmd.MarkFlagRequired("to")
+	rootCmd.AddCommand(promoteCmd)
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/irevolve/bear/internal/cmd"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rollbackSteps       int
+	rollbackConcurrency int
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <artifact>[@<target>]",
+	Short: "Plan a rollback to a previously deployed commit",
+	Long: `Creates a pin plan for an earlier successful deployment of an artifact.
+
+The commit is taken from the deployment history in bear.lock.yml.
+--steps selects how many distinct successful deployments to go back.
+The artifact is pinned to that commit, so future plans skip it until
+you deploy it with --force. Artifacts with several targets are rolled
+back one target at a time, given as <artifact>@<target>.
+
+Run 'bear apply' afterwards to execute the plan.
+
+Examples:
+  bear rollback user-api             # Previous successful deployment
+  bear rollback user-api --steps 2   # Two deployments back
+  bear rollback user-api --env prod  # Roll back in an environment
+  bear rollback user-api@job         # Roll back one target`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(c *cobra.Command, args []string) error {
+		// Convert to absolute path
+		absDir, err := filepath.Abs(workDir)
+		if err != nil {
+			return fmt.Errorf("invalid path: %w", err)
+		}
+
+		configPath := filepath.Join(absDir, "bear.config.yml")
+		if _, err := os.Stat(configPath); os.IsNotExist(err) {
+			return fmt.Errorf("config file not found: %s", configPath)
+		}
+
+		opts := cmd.Options{
+			Force:          force,
+			Concurrency:    rollbackConcurrency,
+			Verbose:        verbose,
+			Environment:    env,
+			ConfigOverlays: configOverlays,
+		}
+
+		return cmd.Rollback(c.Context(), configPath, args[0], rollbackSteps, opts)
+	},
+}
+
+func init() {
+	rollbackCmd.Flags().IntVar(&rollbackSteps, "steps", 1, "Number of successful deployments to go back")
+	rollbackCmd.Flags().IntVar(&rollbackConcurrency, "concurrency", 10, "Maximum number of parallel validation jobs")
+	rootCmd.AddCommand(rollbackCmd)
+}
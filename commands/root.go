@@ -1,7 +1,11 @@
 package commands
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/irevolve/bear/internal"
 	"github.com/spf13/cobra"
@@ -15,6 +19,14 @@ var (
 	workDir string
 	force   bool
 	verbose bool
+	env     string
+
+	configOverlays []string
+
+	// Artifact selection flags of plan and list
+	selectTags  []string
+	excludeTags []string
+	withDeps    bool
 )
 
 var rootCmd = &cobra.Command{
@@ -37,11 +49,29 @@ Usage:
   bear list                      List all artifacts
   bear list --tree               Show dependency tree
   bear plan                      Validate changes and create deployment plan
-  bear apply                     Execute the deployment plan`,
+  bear apply                     Execute the deployment plan
+  bear promote --from a --to b   Deploy the versions of one environment to another`,
 }
 
+// Execute runs the root command. SIGINT and SIGTERM cancel the context of
+// the command, so that running steps are stopped and completed deployments
+// are recorded. A second signal terminates Bear immediately.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			stop() // Restore the default behavior for a second signal
+			fmt.Fprintln(os.Stderr, "\nInterrupted, stopping running steps. Press Ctrl+C again to quit immediately.")
+		case <-done:
+		}
+	}()
+
+	return rootCmd.ExecuteContext(ctx)
 }
 
 func init() {
@@ -56,7 +86,16 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&workDir, "dir", "d", ".", "Path to project directory")
 	rootCmd.PersistentFlags().BoolVarP(&force, "force", "f", false, "Force operation, ignoring pinned artifacts")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose debug output")
+	rootCmd.PersistentFlags().StringVarP(&env, "env", "e", "", "Environment to use (from environments in bear.config.yml)")
+	rootCmd.PersistentFlags().StringArrayVar(&configOverlays, "config-overlay", nil, "Config file applied on top of bear.config.yml (repeatable)")
 
 	// Version template
 	rootCmd.SetVersionTemplate(fmt.Sprintf("bear version %s\n", Version))
 }
+
+// addSelectFlags adds the flags that select artifacts by tag
+func addSelectFlags(c *cobra.Command) {
+	c.Flags().StringArrayVar(&selectTags, "tag", nil, "Select artifacts with a tag, join tags with + to require all (repeatable)")
+	c.Flags().StringArrayVar(&excludeTags, "exclude-tag", nil, "Leave out artifacts with a tag, join tags with + to require all (repeatable)")
+	c.Flags().BoolVar(&withDeps, "with-deps", false, "Also select the dependencies of selected artifacts")
+}
@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"github.com/irevolve/bear/internal/cmd"
+	"github.com/spf13/cobra"
+)
+
+var schemaOut string
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema [config|artifact|lib]",
+	Short: "Print JSON Schemas of the config files",
+	Long: `Prints the JSON Schema of bear.config.yml, bear.artifact.yml or
+bear.lib.yml, e.g. for validation and completion in editors.
+
+With --out, the schemas are written to a directory instead.
+
+Examples:
+  bear schema artifact > bear.artifact.schema.json
+  bear schema --out .bear/schemas     # Write all schemas`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"config", "artifact", "lib"},
+	RunE: func(c *cobra.Command, args []string) error {
+		fileType := ""
+		if len(args) > 0 {
+			fileType = args[0]
+		}
+		return cmd.Schema(fileType, schemaOut)
+	},
+}
+
+func init() {
+	schemaCmd.Flags().StringVarP(&schemaOut, "out", "o", "", "Write all schemas to this directory")
+	rootCmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/irevolve/bear/internal/cmd"
+	"github.com/spf13/cobra"
+)
+
+var showOutput string
+
+var showCmd = &cobra.Command{
+	Use:   "show [planfile]",
+	Short: "Show a saved deployment plan",
+	Long: `Renders a plan file created by 'bear plan'.
+
+Without an argument, the plan in .bear/plan.yml is shown.
+
+Formats:
+  text       Human-readable output (default)
+  json       JSON, same schema as 'bear plan --output json'
+  yaml       YAML, same schema as .bear/plan.yml
+  markdown   Markdown, e.g. for pull request comments
+
+Examples:
+  bear show                          # Show the current plan
+  bear show --output markdown        # Render as Markdown
+  bear show plan.json --output yaml  # Convert a saved plan`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(c *cobra.Command, args []string) error {
+		// Convert to absolute path
+		absDir, err := filepath.Abs(workDir)
+		if err != nil {
+			return fmt.Errorf("invalid path: %w", err)
+		}
+
+		planPath := ""
+		if len(args) > 0 {
+			planPath = args[0]
+		}
+
+		return cmd.Show(absDir, planPath, showOutput)
+	},
+}
+
+func init() {
+	showCmd.Flags().StringVarP(&showOutput, "output", "o", "text", "Output format (text, json, yaml, markdown)")
+	rootCmd.AddCommand(showCmd)
+}
@@ -0,0 +1,69 @@
+// Package cache stores the results of successful validations, keyed by a
+// hash of everything the validation depends on, so that unchanged artifacts
+// are not validated again.
+package cache
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+// Entry is a cached validation result
+type Entry struct {
+	Artifact  string    `json:"artifact"`
+	Validated time.Time `json:"validated"`
+	Warnings  []string  `json:"warnings,omitempty"` // Failures of steps with continue_on_error
+}
+
+// Backend stores cache entries
+type Backend interface {
+	// Get returns the entry for key, or nil on a cache miss.
+	Get(key string) (*Entry, error)
+	// Put stores the entry for key.
+	Put(key string, entry Entry) error
+}
+
+// validKey matches the hex encoded SHA-256 keys used by Bear
+var validKey = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// checkKey rejects keys that are not safe to use as file name or URL path
+func checkKey(key string) error {
+	if !validKey.MatchString(key) {
+		return fmt.Errorf("invalid cache key '%s'", key)
+	}
+	return nil
+}
+
+// Dir returns the local cache directory of a workspace
+func Dir(rootPath string) string {
+	return filepath.Join(config.BearDir(rootPath), "cache")
+}
+
+// New creates the backend configured in bear.config.yml. It returns nil if
+// caching is disabled.
+func New(cfg config.Cache, rootPath, project string) (Backend, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocalBackend(Dir(rootPath)), nil
+	case "http":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("cache: url is required for the http backend")
+		}
+		token := ""
+		if cfg.TokenEnv != "" {
+			token = os.Getenv(cfg.TokenEnv)
+		}
+		return NewHTTPBackend(strings.TrimRight(cfg.URL, "/")+"/"+url.PathEscape(project), token), nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("cache: unknown backend '%s' (use local, http or none)", cfg.Backend)
+	}
+}
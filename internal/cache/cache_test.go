@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+const testKey = "2d53cbe8e8d107f232ff6a23719bb2142e0777efa2857c61abb0b8a3a9f529b3"
+
+// cacheServer is an in-memory implementation of the HTTP cache protocol
+type cacheServer struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func (s *cacheServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		data, ok := s.entries[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodPut:
+		var entry Entry
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := json.Marshal(entry)
+		s.entries[r.URL.Path] = data
+	}
+}
+
+func testBackend(t *testing.T, b Backend) {
+	t.Helper()
+
+	entry, err := b.Get(testKey)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if entry != nil {
+		t.Fatalf("expected a miss, got %v", entry)
+	}
+
+	validated := time.Date(2026, 1, 4, 10, 0, 0, 0, time.UTC)
+	if err := b.Put(testKey, Entry{Artifact: "api", Validated: validated, Warnings: []string{"Lint: exit status 1"}}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	entry, err = b.Get(testKey)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if entry == nil {
+		t.Fatal("expected a hit")
+	}
+	if entry.Artifact != "api" || !entry.Validated.Equal(validated) || len(entry.Warnings) != 1 {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+
+	if _, err := b.Get("../../etc/passwd"); err == nil {
+		t.Error("expected an invalid key to be rejected")
+	}
+}
+
+func TestLocalBackend(t *testing.T) {
+	testBackend(t, NewLocalBackend(filepath.Join(t.TempDir(), "cache")))
+}
+
+func TestHTTPBackend(t *testing.T) {
+	srv := &cacheServer{entries: make(map[string][]byte)}
+	server := httptest.NewServer(srv)
+	defer server.Close()
+
+	backend, err := New(config.Cache{Backend: "http", URL: server.URL + "/"}, t.TempDir(), "my project")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	testBackend(t, backend)
+
+	if _, ok := srv.entries["/my project/"+testKey]; !ok {
+		t.Errorf("expected the entry to be stored under the project, got %v", srv.entries)
+	}
+}
+
+func TestHTTPBackend_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	b := NewHTTPBackend(server.URL, "")
+	if _, err := b.Get(testKey); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("expected HTTP 500 error, got %v", err)
+	}
+	if err := b.Put(testKey, Entry{}); err == nil {
+		t.Error("expected Put to fail")
+	}
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		cfg     config.Cache
+		wantNil bool
+		wantErr bool
+	}{
+		{"default", config.Cache{}, false, false},
+		{"local", config.Cache{Backend: "local"}, false, false},
+		{"none", config.Cache{Backend: "none"}, true, false},
+		{"http without url", config.Cache{Backend: "http"}, false, true},
+		{"unknown", config.Cache{Backend: "s3"}, false, true},
+	}
+
+	for _, tt := range tests {
+		b, err := New(tt.cfg, dir, "test")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		if !tt.wantErr && (b == nil) != tt.wantNil {
+			t.Errorf("%s: expected nil backend %v, got %v", tt.name, tt.wantNil, b)
+		}
+	}
+}
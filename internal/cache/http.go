@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HTTPBackend stores entries on a remote server, so that CI runners share
+// validation results.
+//
+// The server is expected to implement:
+//
+//	GET <url>/<key>   200 with the Entry as JSON, 404 on a miss
+//	PUT <url>/<key>   Store the Entry in the body: 200
+type HTTPBackend struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHTTPBackend creates a backend for the cache at url. If token is set,
+// it is sent as bearer token.
+func NewHTTPBackend(url, token string) *HTTPBackend {
+	return &HTTPBackend{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Get returns the entry for key, or nil on a cache miss
+func (b *HTTPBackend) Get(key string) (*Entry, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+
+	resp, err := b.do(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var entry Entry
+		if err := json.NewDecoder(resp.Body).Decode(&entry); err != nil {
+			return nil, fmt.Errorf("invalid cache response: %w", err)
+		}
+		return &entry, nil
+	case http.StatusNotFound, http.StatusNoContent:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("failed to read cache entry: HTTP %d", resp.StatusCode)
+	}
+}
+
+// Put stores the entry for key
+func (b *HTTPBackend) Put(key string, entry Entry) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.do(http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	default:
+		return fmt.Errorf("failed to store cache entry: HTTP %d", resp.StatusCode)
+	}
+}
+
+// do sends a request for a cache entry
+func (b *HTTPBackend) do(method, key string, body []byte) (*http.Response, error) {
+	url := b.url + "/" + key
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+	req.Header.Set("User-Agent", "Bear-CI/1.0")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cache request to %s failed: %w", url, err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// LocalBackend stores every entry in a JSON file named after its key
+type LocalBackend struct {
+	dir string
+}
+
+// NewLocalBackend creates a backend that stores entries in dir
+func NewLocalBackend(dir string) *LocalBackend {
+	return &LocalBackend{dir: dir}
+}
+
+// Get returns the entry for key, or nil on a cache miss
+func (b *LocalBackend) Get(key string) (*Entry, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(b.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// A corrupt entry is a miss, it is overwritten by the next Put
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// Put stores the entry for key. The file is replaced atomically, so
+// concurrent readers never see a partial entry.
+func (b *LocalBackend) Put(key string, entry Entry) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(b.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), b.path(key))
+}
+
+func (b *LocalBackend) path(key string) string {
+	return filepath.Join(b.dir, key+".json")
+}
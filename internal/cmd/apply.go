@@ -1,8 +1,8 @@
 package cmd
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -13,8 +13,7 @@ import (
 	"github.com/irevolve/bear/internal/config"
 )
 
-func ApplyWithOptions(configPath string, opts Options) error {
-	ctx := context.Background()
+func ApplyWithOptions(ctx context.Context, configPath string, opts Options) (err error) {
 	p := NewPrinter()
 
 	rootPath := filepath.Dir(configPath)
@@ -32,18 +31,63 @@ func ApplyWithOptions(configPath string, opts Options) error {
 		return fmt.Errorf("error reading plan file: %w", err)
 	}
 
+	if opts.Environment != "" && opts.Environment != planFile.Environment {
+		return fmt.Errorf("plan was created for environment '%s', not '%s'. Run 'bear plan --env %s' first",
+			displayEnvironment(planFile.Environment), opts.Environment, opts.Environment)
+	}
+
+	cfg, err := internal.Load(configPath, opts.ConfigOverlays)
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	executor, err := resolveExecutor(cfg.Executor, opts)
+	if err != nil {
+		return err
+	}
+	dryRun := isDryRun(executor)
+	var stream *Printer
+	if streamOutput(opts, p) {
+		stream = p
+	}
+
+	// Secrets are resolved now and only passed to the steps
+	secrets, err := resolveSecrets(cfg, rootPath, planFile.Environment)
+	if err != nil {
+		return err
+	}
+
+	// Refuse to run while someone else holds the state lock
+	unlock, err := acquireStateLock(cfg, rootPath, planFile.Environment, "apply")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if len(planFile.Artifacts) == 0 {
 		p.Println("Plan contains no artifacts to deploy.")
 		config.RemovePlan(rootPath)
 		return nil
 	}
 
-	// Check if HEAD has moved since plan was created
-	currentCommit := internal.GetCurrentCommit(rootPath)
-	if currentCommit != "" && planFile.Commit != "" && currentCommit != planFile.Commit {
-		p.Warning(fmt.Sprintf("HEAD has moved since plan was created (plan: %s, current: %s)",
-			planFile.Commit[:min(7, len(planFile.Commit))],
-			currentCommit[:min(7, len(currentCommit))]))
+	// Check whether the inputs of the plan changed since it was created
+	fingerprint, err := computeFingerprint(cfg, rootPath, planFile.Environment)
+	if err != nil {
+		return err
+	}
+	if drift := planDrift(planFile, fingerprint, internal.GetCurrentCommit(rootPath), rootPath); len(drift) > 0 {
+		p.Blank()
+		if !opts.AllowStale {
+			p.Printf("  %s\n", p.red("The plan is stale:"))
+			for _, d := range drift {
+				p.Printf("    %s %s\n", p.red("•"), d)
+			}
+			p.Hint("Run 'bear plan' again, or use --allow-stale to apply it anyway.")
+			return fmt.Errorf("plan is stale")
+		}
+		for _, d := range drift {
+			p.Warning(d)
+		}
 		p.Blank()
 	}
 
@@ -51,93 +95,165 @@ func ApplyWithOptions(configPath string, opts Options) error {
 
 	p.BearHeader("Apply")
 
+	if planFile.Environment != "" {
+		p.Printf("  Environment: %s\n", p.bold(planFile.Environment))
+	}
+
+	// Hooks run once around the deployments, post_apply and on_failure also after errors
+	hooks := &hookRunner{
+		p:        p,
+		executor: executor,
+		stream:   stream,
+		rootPath: rootPath,
+		vars:     withSecrets(mergeVars(cfg, "", "", planFile.Environment, nil), secrets),
+		verbose:  opts.Verbose || dryRun,
+	}
+	summary := &hookSummary{
+		Project:     cfg.Name,
+		Environment: planFile.Environment,
+		Commit:      planFile.Commit,
+		Status:      "running",
+	}
+	for _, a := range planFile.Artifacts {
+		summary.Deploy = append(summary.Deploy, a.Key())
+	}
+	defer func() { hooks.finish(ctx, hookPostApply, cfg.Hooks, summary, err) }()
+	if err := hooks.run(ctx, hookPreApply, cfg.Hooks.PreApply, *summary); err != nil {
+		return err
+	}
+
 	// Load lock file for updates
-	lockPath := filepath.Join(rootPath, "bear.lock.yml")
+	lockPath := config.LockFilePath(rootPath, planFile.Environment)
 	lockFile, err := config.LoadLock(lockPath)
 	if err != nil {
 		return fmt.Errorf("error loading lock file: %w", err)
 	}
 
-	// Deploy all artifacts in parallel
-	p.PhaseHeader(fmt.Sprintf("Deploying %d artifact(s)", len(planFile.Artifacts)))
-
-	type deployResult struct {
-		name   string
-		output string
-		err    error
+	// Order deployments by their dependencies
+	waves, err := deployWaves(planFile.Artifacts)
+	if err != nil {
+		return fmt.Errorf("error ordering deployments: %w", err)
 	}
-	results := make([]deployResult, len(planFile.Artifacts))
 
-	errs := RunParallel(ctx, opts.Concurrency, len(planFile.Artifacts), func(ctx context.Context, i int) error {
-		artifact := planFile.Artifacts[i]
-		var combinedOutput bytes.Buffer
+	p.PhaseHeader(fmt.Sprintf("Deploying %d artifact(s)", len(planFile.Artifacts)))
 
-		for _, step := range artifact.Steps {
-			var stdout, stderr bytes.Buffer
-			execErr := ExecuteStep(ctx, step.Run, artifact.Path, artifact.Vars, &stdout, &stderr)
+	results := make([]deployResult, len(planFile.Artifacts))
+	failed := make(map[string]bool) // Artifacts with a target that did not deploy (failed or skipped)
 
-			if opts.Verbose {
-				combinedOutput.WriteString(fmt.Sprintf("  → %s\n", step.Name))
-				combinedOutput.Write(stdout.Bytes())
-				combinedOutput.Write(stderr.Bytes())
+	var failures, blocked, notStarted []string
+	deployed := 0
+	for w, wave := range waves {
+		if len(waves) > 1 {
+			if w > 0 {
+				p.Blank()
 			}
+			p.Printf("  %s\n", p.dim(fmt.Sprintf("Wave %d/%d", w+1, len(waves))))
+		}
 
-			if execErr != nil {
-				combinedOutput.Write(stdout.Bytes())
-				combinedOutput.Write(stderr.Bytes())
+		// Skip dependents of failed deployments
+		var runnable []int
+		for _, i := range wave {
+			artifact := planFile.Artifacts[i]
+			if ctx.Err() != nil {
+				results[i] = deployResult{name: artifact.Key(), skipped: true, err: errCancelled}
+				continue
+			}
+			if dep := failedDependency(artifact, failed); dep != "" {
 				results[i] = deployResult{
-					name:   artifact.Name,
-					output: combinedOutput.String(),
-					err:    fmt.Errorf("%s: %w", step.Name, execErr),
+					name:    artifact.Key(),
+					skipped: true,
+					err:     fmt.Errorf("dependency '%s' failed", dep),
 				}
-				return execErr
+				continue
 			}
+			runnable = append(runnable, i)
 		}
 
-		results[i] = deployResult{
-			name:   artifact.Name,
-			output: combinedOutput.String(),
-		}
-		return nil
-	})
-
-	// Print results in order and update lock file for successful deployments
-	var failures []string
-	deployed := 0
-	for i, res := range results {
-		artifact := planFile.Artifacts[i]
-		if res.err != nil {
-			p.FailureWithOutput(fmt.Sprintf("%s → %s — %s", res.name, artifact.Target, res.err), res.output)
-			failures = append(failures, res.name)
-		} else {
-			p.Success(fmt.Sprintf("%s → %s", res.name, artifact.Target))
-			if opts.Verbose && res.output != "" {
-				p.ErrorBox(res.output)
-			}
-			deployed++
-
-			// Update lock file
-			version := deployVersion[:min(7, len(deployVersion))]
-			if artifact.Pinned {
-				pinCommit := artifact.PinCommit
-				if pinCommit == "" {
-					pinCommit = deployVersion
+		RunParallel(ctx, opts.Concurrency, len(runnable), func(ctx context.Context, j int) error {
+			i := runnable[j]
+			results[i] = deployArtifact(ctx, executor, stream, rootPath, planFile.Artifacts[i], secrets, opts.Verbose || dryRun)
+			return results[i].err
+		})
+
+		// Print results in order and update lock file for successful deployments
+		for _, i := range wave {
+			res := results[i]
+			artifact := planFile.Artifacts[i]
+			switch {
+			case res.skipped && errors.Is(res.err, errCancelled):
+				p.Skip(fmt.Sprintf("%s → %s — not started: interrupted", artifact.Name, artifact.Target))
+				notStarted = append(notStarted, res.name)
+				failed[artifact.Name] = true
+			case res.skipped:
+				p.Skip(fmt.Sprintf("%s → %s — skipped: %s", artifact.Name, artifact.Target, res.err))
+				blocked = append(blocked, res.name)
+				failed[artifact.Name] = true
+			case res.err != nil:
+				p.FailureWithOutput(fmt.Sprintf("%s → %s — %s", artifact.Name, artifact.Target, res.err), res.output)
+				failures = append(failures, res.name)
+				failed[artifact.Name] = true
+
+				// Record the failed attempt in the history
+				commit := deployedCommit(artifact, deployVersion)
+				lockFile.RecordFailure(artifact.Key(), commit, artifact.Target, commit[:min(7, len(commit))])
+			default:
+				p.Success(fmt.Sprintf("%s → %s", artifact.Name, artifact.Target))
+				printStepWarnings(p, res.warnings)
+				if (opts.Verbose || dryRun) && stream == nil && res.output != "" {
+					p.ErrorBox(res.output)
 				}
-				lockFile.UpdateArtifactPinned(artifact.Name, pinCommit, artifact.Target, version)
-			} else {
-				lockFile.UpdateArtifact(artifact.Name, deployVersion, artifact.Target, version)
+				deployed++
+
+				// Update lock file
+				commit := deployedCommit(artifact, deployVersion)
+				version := commit[:min(7, len(commit))]
+				key := artifact.Key()
+				if artifact.Pinned {
+					lockFile.UpdateArtifactPinned(key, commit, artifact.Target, version)
+				} else {
+					lockFile.UpdateArtifact(key, commit, artifact.Target, version)
+				}
+				if artifact.TreeHash != "" {
+					lockFile.SetTreeHash(key, artifact.TreeHash)
+				}
+				lockFile.SetOutputs(key, redactOutputs(selectOutputs(res.outputs, artifact.Outputs)))
 			}
 		}
 	}
 
-	failedErrs := CollectErrors(errs)
-	if len(failedErrs) > 0 {
+	summary.Failed = failures
+	summary.Skipped = append(append([]string(nil), blocked...), notStarted...)
+	for i, a := range planFile.Artifacts {
+		if results[i].err == nil && !results[i].skipped {
+			summary.Deployed = append(summary.Deployed, a.Key())
+		}
+	}
+
+	if len(failures) > 0 {
 		p.Blank()
 		p.Printf("  %s\n", p.red(fmt.Sprintf("Deployment failed for: %s", strings.Join(failures, ", "))))
 	}
+	if len(blocked) > 0 {
+		if len(failures) == 0 {
+			p.Blank()
+		}
+		p.Printf("  %s\n", p.yellow(fmt.Sprintf("Skipped due to failed dependencies: %s", strings.Join(blocked, ", "))))
+	}
 
-	// Save lock file (even if some failed, save successful ones)
-	if deployed > 0 {
+	interrupted := ctx.Err() != nil
+	if interrupted {
+		p.Blank()
+		p.Printf("  %s\n", p.yellow("Interrupted, completed deployments are recorded in the lock file."))
+		if len(notStarted) > 0 {
+			p.Printf("  %s\n", p.yellow(fmt.Sprintf("Not started: %s", strings.Join(notStarted, ", "))))
+		}
+	}
+
+	// Save lock file (even if some failed, save successful ones and the history)
+	if dryRun {
+		p.Blank()
+		p.Printf("  %s\n", p.yellow("Dry run: steps were not executed, lock file and plan left unchanged."))
+	} else if deployed > 0 || len(failures) > 0 {
 		if err := lockFile.Save(lockPath); err != nil {
 			return fmt.Errorf("error saving lock file: %w", err)
 		}
@@ -146,13 +262,13 @@ func ApplyWithOptions(configPath string, opts Options) error {
 
 		// Auto-commit (default behavior, disabled with --no-commit)
 		if !opts.NoCommit {
-			var deployedArtifacts []config.PlanArtifact
+			var deployedNames []string
 			for i, a := range planFile.Artifacts {
 				if results[i].err == nil {
-					deployedArtifacts = append(deployedArtifacts, a)
+					deployedNames = append(deployedNames, a.Key())
 				}
 			}
-			if err := commitLockFile(rootPath, lockPath, deployedArtifacts); err != nil {
+			if err := commitLockFile(rootPath, lockPath, planFile.Environment, deployedNames, failures); err != nil {
 				p.Warning(fmt.Sprintf("Failed to commit lock file: %v", err))
 			} else {
 				p.Printf("  %s\n", p.dim("Lock file committed with [skip ci]"))
@@ -161,7 +277,9 @@ func ApplyWithOptions(configPath string, opts Options) error {
 	}
 
 	// Remove plan file after apply
-	config.RemovePlan(rootPath)
+	if !dryRun {
+		config.RemovePlan(rootPath)
+	}
 
 	// Summary
 	parts := []string{}
@@ -171,25 +289,60 @@ func ApplyWithOptions(configPath string, opts Options) error {
 	if len(failures) > 0 {
 		parts = append(parts, p.SummaryFailed(len(failures)))
 	}
-	if planFile.TotalSkips > 0 {
-		parts = append(parts, p.SummarySkipped(planFile.TotalSkips))
+	if skipped := planFile.TotalSkips + len(blocked) + len(notStarted); skipped > 0 {
+		parts = append(parts, p.SummarySkipped(skipped))
 	}
 	p.Summary(parts...)
 
-	if len(failedErrs) > 0 {
-		return fmt.Errorf("deployment failed for %d artifact(s)", len(failedErrs))
+	if interrupted {
+		return fmt.Errorf("apply interrupted")
+	}
+	if len(failures) > 0 || len(blocked) > 0 {
+		return fmt.Errorf("deployment failed for %d artifact(s)", len(failures)+len(blocked))
 	}
 
 	return nil
 }
 
+// deployResult holds the outcome of deploying a single artifact
+type deployResult struct {
+	name     string
+	output   string
+	err      error
+	warnings []string          // Failures of steps with continue_on_error
+	outputs  map[string]string // Step outputs
+	skipped  bool              // Not attempted because a dependency failed
+}
+
+// deployArtifact runs all deploy steps of an artifact sequentially
+func deployArtifact(ctx context.Context, executor Executor, stream *Printer, rootPath string, artifact config.PlanArtifact, secrets map[string]string, verbose bool) deployResult {
+	vars := withSecrets(artifact.Vars, secrets)
+	scope := newStepScope(executor, rootPath, artifact.Name, artifact.Path, vars, artifact.ChangedFiles, artifact.ChangesUnknown)
+	scope.stream = stream
+	res := runSteps(ctx, artifact.Steps, scope, verbose)
+
+	return deployResult{
+		name:     artifact.Key(),
+		output:   res.output,
+		err:      res.err,
+		warnings: res.warnings,
+		outputs:  res.outputs,
+	}
+}
+
 // commitLockFile commits the lock file with [skip ci] to prevent CI loops
-func commitLockFile(rootPath, lockPath string, deployed []config.PlanArtifact) error {
-	var names []string
-	for _, d := range deployed {
-		names = append(names, d.Name)
+func commitLockFile(rootPath, lockPath, env string, deployed, failed []string) error {
+	subject := "chore(bear): update lock file [skip ci]"
+	if env != "" {
+		subject = fmt.Sprintf("chore(bear): update %s lock file [skip ci]", env)
+	}
+	msg := subject + "\n"
+	if len(deployed) > 0 {
+		msg += fmt.Sprintf("\nDeployed: %s", strings.Join(deployed, ", "))
+	}
+	if len(failed) > 0 {
+		msg += fmt.Sprintf("\nFailed: %s", strings.Join(failed, ", "))
 	}
-	msg := fmt.Sprintf("chore(bear): update lock file [skip ci]\n\nDeployed: %s", strings.Join(names, ", "))
 
 	addCmd := exec.Command("git", "add", lockPath)
 	addCmd.Dir = rootPath
@@ -211,3 +364,23 @@ func commitLockFile(rootPath, lockPath string, deployed []config.PlanArtifact) e
 
 	return nil
 }
+
+// deployedCommit returns the commit that a plan artifact deploys
+func deployedCommit(artifact config.PlanArtifact, planCommit string) string {
+	switch {
+	case artifact.Pinned && artifact.PinCommit != "":
+		return artifact.PinCommit
+	case artifact.Commit != "":
+		return artifact.Commit
+	default:
+		return planCommit
+	}
+}
+
+// displayEnvironment returns a printable name for an environment
+func displayEnvironment(env string) string {
+	if env == "" {
+		return "default"
+	}
+	return env
+}
@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/irevolve/bear/internal"
+	"github.com/irevolve/bear/internal/config"
+)
+
+const testConfig = `name: test
+targets:
+  ok:
+    outputs: [URL]
+    steps:
+      - name: Deploy
+        run: deploy
+`
+
+// writeTestPlan writes a config and a plan for the given artifacts to dir
+func writeTestPlan(t *testing.T, dir string, artifacts []config.PlanArtifact) string {
+	t.Helper()
+	return writeTestPlanWithConfig(t, dir, testConfig, artifacts)
+}
+
+// writeTestPlanWithConfig writes the given config and a plan for the artifacts to dir
+func writeTestPlanWithConfig(t *testing.T, dir, content string, artifacts []config.PlanArtifact) string {
+	t.Helper()
+
+	configPath := filepath.Join(dir, "bear.config.yml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := internal.Load(configPath, nil)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	fingerprint, err := computeFingerprint(cfg, dir, "")
+	if err != nil {
+		t.Fatalf("failed to compute fingerprint: %v", err)
+	}
+
+	planFile := config.NewPlanFile("")
+	planFile.Fingerprint = fingerprint
+	planFile.Artifacts = artifacts
+	planFile.ToDeploy = len(artifacts)
+	if err := config.WritePlan(dir, planFile); err != nil {
+		t.Fatalf("failed to write plan: %v", err)
+	}
+
+	return configPath
+}
+
+func TestApplyWithOptions_FakeExecutor(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writeTestPlan(t, dir, []config.PlanArtifact{
+		{Name: "db", Path: dir, Target: "ok", Action: "deploy", Steps: []config.Step{{Name: "Deploy", Run: "deploy db"}}},
+		{Name: "api", Path: dir, Target: "ok", Action: "deploy", Depends: []string{"db"}, Steps: []config.Step{{Name: "Deploy", Run: "deploy api"}}},
+		{Name: "web", Path: dir, Target: "ok", Action: "deploy", Outputs: []string{"URL"}, Steps: []config.Step{{Name: "Deploy", Run: "deploy web"}}},
+	})
+
+	fake := &FakeExecutor{Results: map[string]FakeResult{
+		"deploy db":  {Err: errors.New("exit status 1")},
+		"deploy web": {Outputs: map[string]string{"URL": "https://web", "TMP": "x"}},
+	}}
+
+	err := ApplyWithOptions(context.Background(), configPath, Options{NoCommit: true, StepExecutor: fake})
+	if err == nil {
+		t.Fatal("expected apply to fail")
+	}
+
+	for _, call := range fake.Calls() {
+		if call.Artifact == "api" {
+			t.Error("expected api to be skipped after db failed")
+		}
+	}
+
+	lock, err := config.LoadLock(config.LockFilePath(dir, ""))
+	if err != nil {
+		t.Fatalf("failed to load lock: %v", err)
+	}
+	if _, ok := lock.Artifacts["db"]; ok {
+		t.Error("expected failed db not to be recorded as deployed")
+	}
+	if _, ok := lock.Artifacts["api"]; ok {
+		t.Error("expected skipped api not to be recorded")
+	}
+	web, ok := lock.Artifacts["web"]
+	if !ok {
+		t.Fatal("expected web to be recorded")
+	}
+	if len(web.Outputs) != 1 || web.Outputs["URL"] != "https://web" {
+		t.Errorf("expected only the URL output to be recorded, got %v", web.Outputs)
+	}
+	if len(lock.History["db"]) != 1 || lock.History["db"][0].Result != config.ResultFailed {
+		t.Errorf("expected failed db attempt in history, got %v", lock.History["db"])
+	}
+
+	if config.PlanExists(dir) {
+		t.Error("expected plan to be removed after apply")
+	}
+}
+
+func TestApplyWithOptions_SeveralTargets(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writeTestPlan(t, dir, []config.PlanArtifact{
+		{Name: "api", Path: dir, Target: "ok", LockKey: "api@ok", Action: "deploy", Steps: []config.Step{{Name: "Deploy", Run: "deploy service"}}},
+		{Name: "api", Path: dir, Target: "job", LockKey: "api@job", Action: "deploy", Steps: []config.Step{{Name: "Deploy", Run: "deploy job"}}},
+		{Name: "web", Path: dir, Target: "ok", Action: "deploy", Depends: []string{"api"}, Steps: []config.Step{{Name: "Deploy", Run: "deploy web"}}},
+	})
+
+	fake := &FakeExecutor{Results: map[string]FakeResult{
+		"deploy job": {Err: errors.New("exit status 1")},
+	}}
+
+	if err := ApplyWithOptions(context.Background(), configPath, Options{NoCommit: true, StepExecutor: fake}); err == nil {
+		t.Fatal("expected apply to fail")
+	}
+
+	lock, err := config.LoadLock(config.LockFilePath(dir, ""))
+	if err != nil {
+		t.Fatalf("failed to load lock: %v", err)
+	}
+	if entry, ok := lock.Artifacts["api@ok"]; !ok || entry.Target != "ok" {
+		t.Errorf("expected api@ok to be recorded, got %+v", lock.Artifacts)
+	}
+	if _, ok := lock.Artifacts["api@job"]; ok {
+		t.Error("expected failed api@job not to be recorded as deployed")
+	}
+	if len(lock.History["api@job"]) != 1 {
+		t.Errorf("expected failed api@job attempt in history, got %v", lock.History)
+	}
+	if _, ok := lock.Artifacts["web"]; ok {
+		t.Error("expected web to be skipped after a target of api failed")
+	}
+}
+
+func TestApplyWithOptions_DryRun(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writeTestPlan(t, dir, []config.PlanArtifact{
+		{Name: "web", Path: dir, Target: "ok", Action: "deploy", Steps: []config.Step{{Name: "Deploy", Run: "exit 1"}}},
+	})
+
+	if err := ApplyWithOptions(context.Background(), configPath, Options{NoCommit: true, Executor: "dry-run"}); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+
+	if _, err := os.Stat(config.LockFilePath(dir, "")); !os.IsNotExist(err) {
+		t.Error("expected dry run not to write the lock file")
+	}
+	if !config.PlanExists(dir) {
+		t.Error("expected dry run to keep the plan")
+	}
+}
+
+func TestApplyWithOptions_Secrets(t *testing.T) {
+	defer internal.ResetSecrets()
+	t.Setenv("BEAR_TEST_TOKEN", "s3cret-token")
+
+	dir := t.TempDir()
+	content := testConfig + `secrets:
+  TOKEN:
+    env: BEAR_TEST_TOKEN
+`
+	configPath := writeTestPlanWithConfig(t, dir, content, []config.PlanArtifact{
+		{Name: "web", Path: dir, Target: "ok", Action: "deploy", Outputs: []string{"URL"},
+			Vars: map[string]string{"TOKEN": "from-vars"}, Steps: []config.Step{{Name: "Deploy", Run: "deploy web"}}},
+	})
+
+	fake := &FakeExecutor{Results: map[string]FakeResult{
+		"deploy web": {Outputs: map[string]string{"URL": "https://web?token=s3cret-token"}},
+	}}
+
+	if err := ApplyWithOptions(context.Background(), configPath, Options{NoCommit: true, StepExecutor: fake}); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 call, got %d", len(calls))
+	}
+	if calls[0].Vars["TOKEN"] != "s3cret-token" {
+		t.Errorf("expected secret to override var, got '%s'", calls[0].Vars["TOKEN"])
+	}
+
+	lock, err := config.LoadLock(config.LockFilePath(dir, ""))
+	if err != nil {
+		t.Fatalf("failed to load lock: %v", err)
+	}
+	if got := lock.Artifacts["web"].Outputs["URL"]; got != "https://web?token=***" {
+		t.Errorf("expected secret to be masked in lock outputs, got '%s'", got)
+	}
+}
+
+func TestApplyWithOptions_MissingSecret(t *testing.T) {
+	dir := t.TempDir()
+	content := testConfig + `secrets:
+  TOKEN:
+    env: BEAR_TEST_MISSING_TOKEN
+  KEY:
+    file: missing.key
+`
+	configPath := writeTestPlanWithConfig(t, dir, content, []config.PlanArtifact{
+		{Name: "web", Path: dir, Target: "ok", Action: "deploy", Steps: []config.Step{{Name: "Deploy", Run: "deploy web"}}},
+	})
+
+	fake := &FakeExecutor{}
+	err := ApplyWithOptions(context.Background(), configPath, Options{NoCommit: true, StepExecutor: fake})
+	if err == nil {
+		t.Fatal("expected apply to fail")
+	}
+	for _, name := range []string{"TOKEN", "KEY"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected error to mention '%s', got: %v", name, err)
+		}
+	}
+	if len(fake.Calls()) != 0 {
+		t.Error("expected no step to run")
+	}
+	if !config.PlanExists(dir) {
+		t.Error("expected plan to be kept")
+	}
+}
+
+// interruptingExecutor cancels the run after the first step, like a signal would
+type interruptingExecutor struct {
+	cancel context.CancelFunc
+	calls  []string
+}
+
+func (e *interruptingExecutor) Execute(ctx context.Context, req StepRequest) error {
+	e.calls = append(e.calls, req.Artifact)
+	e.cancel()
+	return nil
+}
+
+func TestApplyWithOptions_Interrupted(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writeTestPlan(t, dir, []config.PlanArtifact{
+		{Name: "db", Path: dir, Target: "ok", Action: "deploy", Steps: []config.Step{{Name: "Deploy", Run: "deploy db"}}},
+		{Name: "api", Path: dir, Target: "ok", Action: "deploy", Depends: []string{"db"}, Steps: []config.Step{{Name: "Deploy", Run: "deploy api"}}},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	exec := &interruptingExecutor{cancel: cancel}
+
+	err := ApplyWithOptions(ctx, configPath, Options{NoCommit: true, StepExecutor: exec})
+	if err == nil || !strings.Contains(err.Error(), "interrupted") {
+		t.Fatalf("expected apply to be interrupted, got %v", err)
+	}
+	if len(exec.calls) != 1 || exec.calls[0] != "db" {
+		t.Errorf("expected only db to run, got %v", exec.calls)
+	}
+
+	lock, err := config.LoadLock(config.LockFilePath(dir, ""))
+	if err != nil {
+		t.Fatalf("failed to load lock: %v", err)
+	}
+	if _, ok := lock.Artifacts["db"]; !ok {
+		t.Error("expected completed db deployment to be recorded")
+	}
+	if _, ok := lock.Artifacts["api"]; ok {
+		t.Error("expected api not to be recorded")
+	}
+}
+
+func TestApplyWithOptions_ChangesUnknown(t *testing.T) {
+	dir := t.TempDir()
+	steps := []config.Step{{Name: "Migrate", Run: "migrate", If: "changed('migrations/**')"}}
+	configPath := writeTestPlan(t, dir, []config.PlanArtifact{
+		{Name: "api", Path: dir, Target: "ok", Action: "deploy", ChangedFiles: []config.AffectedFile{{Path: "main.go"}}, Steps: steps},
+		{Name: "web", Path: dir, Target: "ok", Action: "deploy", ChangesUnknown: true, Steps: steps},
+	})
+
+	fake := &FakeExecutor{}
+	if err := ApplyWithOptions(context.Background(), configPath, Options{NoCommit: true, StepExecutor: fake}); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 1 || calls[0].Artifact != "web" {
+		t.Errorf("expected only web to migrate, got %v", calls)
+	}
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"sort"
+	"time"
+
+	"github.com/irevolve/bear/internal"
+	"github.com/irevolve/bear/internal/cache"
+	"github.com/irevolve/bear/internal/config"
+)
+
+// validationCache looks up and stores the results of validations. A nil
+// *validationCache disables caching.
+type validationCache struct {
+	backend cache.Backend
+	hashes  map[string]string // Artifact → content hash of the working tree
+	deps    map[string][]string
+	lookup  bool // Whether hits are used, entries are stored either way
+}
+
+// newValidationCache sets up the configured cache for a plan. It returns
+// nil if caching is disabled or the content hashes are unavailable.
+func newValidationCache(cfg *config.Config, rootPath string, artifacts []internal.DiscoveredArtifact, lookup bool) *validationCache {
+	backend, err := cache.New(cfg.Cache, rootPath, cfg.Name)
+	if err != nil {
+		internal.Warn("validation cache disabled", "error", err)
+		return nil
+	}
+	if backend == nil {
+		return nil
+	}
+
+	hashes, err := internal.ContentHashes(rootPath, artifacts)
+	if err != nil {
+		internal.Warn("validation cache disabled, content hashes unavailable", "error", err)
+		return nil
+	}
+
+	deps := make(map[string][]string, len(artifacts))
+	for _, a := range artifacts {
+		deps[a.Artifact.Name] = a.Artifact.Depends
+	}
+
+	return &validationCache{backend: backend, hashes: hashes, deps: deps, lookup: lookup}
+}
+
+// validationKeyInput is everything a validation result depends on
+type validationKeyInput struct {
+	Artifact string            `yaml:"artifact"`
+	Tree     string            `yaml:"tree"`
+	Deps     map[string]string `yaml:"deps,omitempty"` // Transitive dependencies → content hash
+	Steps    []config.Step     `yaml:"steps"`
+	Vars     map[string]string `yaml:"vars,omitempty"`
+	Changed  []string          `yaml:"changed,omitempty"` // Seen by changed() in step conditions
+	Unknown  bool              `yaml:"unknown,omitempty"` // changed() matches every pattern
+}
+
+// key returns the cache key of a validation, or "" if it cannot be cached
+func (c *validationCache) key(name string, steps []config.Step, vars map[string]string, changedFiles []config.AffectedFile, changesUnknown bool) string {
+	if c == nil || c.hashes[name] == "" {
+		return ""
+	}
+
+	input := validationKeyInput{
+		Artifact: name,
+		Tree:     c.hashes[name],
+		Deps:     make(map[string]string),
+		Steps:    steps,
+		Vars:     vars,
+		Unknown:  changesUnknown,
+	}
+	for _, f := range changedFiles {
+		input.Changed = append(input.Changed, f.String())
+	}
+	sort.Strings(input.Changed)
+
+	// Collect transitive dependencies, cycles are reported by bear check
+	queue := append([]string(nil), c.deps[name]...)
+	for len(queue) > 0 {
+		dep := queue[0]
+		queue = queue[1:]
+		if _, seen := input.Deps[dep]; seen || dep == name {
+			continue
+		}
+		input.Deps[dep] = c.hashes[dep]
+		queue = append(queue, c.deps[dep]...)
+	}
+
+	key, err := config.Hash(input)
+	if err != nil {
+		return ""
+	}
+	return key
+}
+
+// get returns the cached result for key, or nil on a miss
+func (c *validationCache) get(key string) *cache.Entry {
+	if c == nil || key == "" || !c.lookup {
+		return nil
+	}
+	entry, err := c.backend.Get(key)
+	if err != nil {
+		internal.Warn("validation cache lookup failed", "error", err)
+		return nil
+	}
+	return entry
+}
+
+// put stores a successful validation
+func (c *validationCache) put(key, name string, warnings []string) {
+	if c == nil || key == "" {
+		return
+	}
+	entry := cache.Entry{
+		Artifact:  name,
+		Validated: time.Now().UTC(),
+		Warnings:  warnings,
+	}
+	if err := c.backend.Put(key, entry); err != nil {
+		internal.Warn("validation cache update failed", "error", err)
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/irevolve/bear/internal/cache"
+	"github.com/irevolve/bear/internal/config"
+)
+
+func TestValidationCache_Key(t *testing.T) {
+	newCache := func(hashes map[string]string) *validationCache {
+		return &validationCache{
+			backend: cache.NewLocalBackend(t.TempDir()),
+			hashes:  hashes,
+			deps:    map[string][]string{"api": {"lib"}, "lib": {"proto"}},
+			lookup:  true,
+		}
+	}
+	steps := []config.Step{{Name: "Test", Run: "go test ./..."}}
+	vars := map[string]string{"GOFLAGS": "-mod=mod"}
+	hashes := map[string]string{"api": "a1", "lib": "l1", "proto": "p1"}
+
+	base := newCache(hashes).key("api", steps, vars, []config.AffectedFile{{Path: "api/main.go"}}, false)
+	if base == "" {
+		t.Fatal("expected a key")
+	}
+	if again := newCache(hashes).key("api", steps, vars, []config.AffectedFile{{Path: "api/main.go"}}, false); again != base {
+		t.Error("expected the key to be deterministic")
+	}
+
+	changes := map[string]string{
+		"transitive dependency": newCache(map[string]string{"api": "a1", "lib": "l1", "proto": "p2"}).key("api", steps, vars, []config.AffectedFile{{Path: "api/main.go"}}, false),
+		"artifact tree":         newCache(map[string]string{"api": "a2", "lib": "l1", "proto": "p1"}).key("api", steps, vars, []config.AffectedFile{{Path: "api/main.go"}}, false),
+		"steps":                 newCache(hashes).key("api", []config.Step{{Name: "Test", Run: "go test -race ./..."}}, vars, []config.AffectedFile{{Path: "api/main.go"}}, false),
+		"vars":                  newCache(hashes).key("api", steps, map[string]string{"GOFLAGS": ""}, []config.AffectedFile{{Path: "api/main.go"}}, false),
+		"changed files":         newCache(hashes).key("api", steps, vars, []config.AffectedFile{{Path: "api/README.md"}}, false),
+		"unknown flag":          newCache(hashes).key("api", steps, vars, []config.AffectedFile{{Path: "api/main.go"}}, true),
+	}
+	for what, key := range changes {
+		if key == base {
+			t.Errorf("expected a different key when the %s changes", what)
+		}
+	}
+
+	if key := newCache(map[string]string{}).key("api", steps, vars, nil, false); key != "" {
+		t.Errorf("expected no key without a content hash, got '%s'", key)
+	}
+	var disabled *validationCache
+	if key := disabled.key("api", steps, vars, nil, false); key != "" {
+		t.Errorf("expected no key with caching disabled, got '%s'", key)
+	}
+}
+
+func TestValidationCache_GetPut(t *testing.T) {
+	c := &validationCache{
+		backend: cache.NewLocalBackend(t.TempDir()),
+		hashes:  map[string]string{"api": "a1"},
+		lookup:  true,
+	}
+	key := c.key("api", nil, nil, nil, false)
+
+	if c.get(key) != nil {
+		t.Fatal("expected a miss")
+	}
+	c.put(key, "api", []string{"Lint: failed"})
+
+	entry := c.get(key)
+	if entry == nil || entry.Artifact != "api" || len(entry.Warnings) != 1 {
+		t.Fatalf("expected the stored entry, got %+v", entry)
+	}
+
+	c.lookup = false
+	if c.get(key) != nil {
+		t.Error("expected no hit with lookups disabled")
+	}
+}
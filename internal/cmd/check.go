@@ -1,12 +1,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/irevolve/bear/internal"
+	"github.com/irevolve/bear/internal/config"
 )
 
 // ValidationResult contains the result of a validation
@@ -23,11 +25,24 @@ func (v *ValidationResult) AddWarning(format string, args ...interface{}) {
 	v.Warnings = append(v.Warnings, fmt.Sprintf(format, args...))
 }
 
+// addErrors adds an error per problem of err, e.g. each unknown field of a file
+func addErrors(v *ValidationResult, prefix string, err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			addErrors(v, prefix, e)
+		}
+		return
+	}
+	v.AddError("%s: %v", prefix, err)
+}
+
 func (v *ValidationResult) HasErrors() bool {
 	return len(v.Errors) > 0
 }
 
-func Check(configPath string) error {
+// Check validates the config, artifacts and dependencies. Declared vars are
+// checked for opts.Environment, or for every environment if none is given.
+func Check(configPath string, opts Options) error {
 	result := &ValidationResult{}
 	p := NewPrinter()
 
@@ -35,10 +50,10 @@ func Check(configPath string) error {
 
 	// 1. Load config
 	p.Printf("  Loading config... ")
-	cfg, err := internal.Load(configPath)
+	cfg, err := internal.Load(configPath, opts.ConfigOverlays)
 	if err != nil {
 		p.Println(p.red("✗"))
-		result.AddError("Failed to load config: %v", err)
+		addErrors(result, "Failed to load config", err)
 		return printCheckResult(p, result)
 	}
 	p.Printf("%s %s\n", p.green("✓"), cfg.Name)
@@ -56,8 +71,14 @@ func Check(configPath string) error {
 	} else {
 		p.Printf("%s %d defined\n", p.green("✓"), len(cfg.Languages))
 		for name, lang := range cfg.Languages {
+			owner := definitionName(cfg, configPath, config.SourceLanguage, "Language", name)
 			if len(lang.Detection.Files) == 0 && lang.Detection.Pattern == "" {
-				result.AddWarning("Language '%s' has no detection rules", name)
+				result.AddWarning("%s has no detection rules", owner)
+			}
+			checkSteps(result, owner, lang.Steps)
+			checkDeclarations(result, owner, lang.Declare)
+			if cfg.Templates {
+				checkTemplates(result, cfg, owner, lang.Vars, lang.Steps)
 			}
 		}
 	}
@@ -71,8 +92,42 @@ func Check(configPath string) error {
 		p.Printf("%s %d defined\n", p.green("✓"), len(cfg.Targets))
 	}
 	targetNames := make(map[string]bool)
-	for name := range cfg.Targets {
+	for name, target := range cfg.Targets {
 		targetNames[name] = true
+		owner := definitionName(cfg, configPath, config.SourceTarget, "Target", name)
+		checkSteps(result, owner, target.Steps)
+		checkDeclarations(result, owner, target.Declare)
+		if cfg.Templates {
+			checkTemplates(result, cfg, owner, target.Vars, target.Steps)
+		}
+	}
+
+	hooks := map[string][]config.Step{
+		hookPrePlan:   cfg.Hooks.PrePlan,
+		hookPostPlan:  cfg.Hooks.PostPlan,
+		hookPreApply:  cfg.Hooks.PreApply,
+		hookPostApply: cfg.Hooks.PostApply,
+		hookOnFailure: cfg.Hooks.OnFailure,
+	}
+	for _, name := range sortedKeys(hooks) {
+		checkSteps(result, fmt.Sprintf("Hook '%s'", name), hooks[name])
+	}
+
+	if cfg.Templates {
+		checkTemplates(result, cfg, "Project", cfg.Vars, nil)
+		for _, env := range sortedKeys(cfg.Environments) {
+			checkTemplates(result, cfg, fmt.Sprintf("Environment '%s'", env), cfg.Environments[env].Vars, nil)
+		}
+	}
+
+	// Check secret definitions, values are only read by apply
+	for _, name := range sortedKeys(cfg.Secrets) {
+		checkSecret(result, "Secret", name, cfg.Secrets[name])
+	}
+	for _, env := range sortedKeys(cfg.Environments) {
+		for _, name := range sortedKeys(cfg.Environments[env].Secrets) {
+			checkSecret(result, fmt.Sprintf("Environment '%s' secret", env), name, cfg.Environments[env].Secrets[name])
+		}
 	}
 
 	// 4. Scan artifacts
@@ -80,7 +135,7 @@ func Check(configPath string) error {
 	artifacts, err := internal.ScanArtifacts(rootPath, cfg)
 	if err != nil {
 		p.Println(p.red("✗"))
-		result.AddError("Failed to scan artifacts: %v", err)
+		addErrors(result, "Failed to scan artifacts", err)
 		return printCheckResult(p, result)
 	}
 	if len(artifacts) == 0 {
@@ -110,19 +165,28 @@ func Check(configPath string) error {
 	p.Printf("  Checking dependencies... ")
 	depErrors := 0
 	for _, a := range artifacts {
-		// Check language detection
-		if a.Language == "unknown" {
+		if cfg.Templates {
+			checkTemplates(result, cfg, fmt.Sprintf("Artifact '%s'", a.Artifact.Name), a.Artifact.Vars, nil)
+			for _, t := range a.Artifact.Targets {
+				checkTemplates(result, cfg, fmt.Sprintf("Artifact '%s' target '%s'", a.Artifact.Name, t.Name), t.Vars, nil)
+			}
+		}
+
+		// Check language, set explicitly or detected
+		if a.Artifact.Language != "" {
+			if _, ok := cfg.Languages[a.Artifact.Language]; !ok {
+				result.AddError("Artifact '%s' references unknown language '%s'", a.Artifact.Name, a.Artifact.Language)
+			}
+		} else if a.Language == "unknown" {
 			result.AddWarning("Artifact '%s' has unknown language", a.Artifact.Name)
+		} else if matches := internal.MatchLanguages(a.Path, cfg.Languages); len(matches) > 1 {
+			result.AddWarning("Artifact '%s' matches several languages (%s), using '%s'. Set language to choose one explicitly",
+				a.Artifact.Name, strings.Join(matches, ", "), a.Language)
 		}
 
 		// Check target (only for non-libs)
 		if !a.Artifact.IsLib {
-			if a.Artifact.Target == "" {
-				result.AddError("Artifact '%s' has no target defined", a.Artifact.Name)
-			} else if !targetNames[a.Artifact.Target] {
-				result.AddError("Artifact '%s' references unknown target '%s'",
-					a.Artifact.Name, a.Artifact.Target)
-			}
+			checkArtifactTargets(result, a.Artifact, targetNames)
 		}
 
 		// Check dependencies
@@ -140,7 +204,48 @@ func Check(configPath string) error {
 		p.Printf("%s %d unresolved\n", p.red("✗"), depErrors)
 	}
 
-	// 7. Check for circular dependencies
+	// 7. Check declared vars
+	p.Printf("  Checking vars... ")
+	envs := []string{opts.Environment}
+	if opts.Environment == "" && len(cfg.Environments) > 0 {
+		envs = sortedKeys(cfg.Environments)
+	}
+	varErrors := 0
+	for _, env := range envs {
+		if err := checkEnvironment(cfg, env); err != nil {
+			result.AddError("%v", err)
+			varErrors++
+			continue
+		}
+		for _, a := range artifacts {
+			// Artifacts with several targets are checked for each of them
+			targets := []string{a.Artifact.Target}
+			if len(a.Artifact.Targets) > 0 {
+				targets = a.Artifact.TargetNames()
+			}
+			for _, target := range targets {
+				owner := fmt.Sprintf("Artifact '%s'", a.Artifact.Name)
+				if len(a.Artifact.Targets) > 0 {
+					owner += fmt.Sprintf(" → %s", target)
+				}
+				if env != "" {
+					owner += fmt.Sprintf(" (env %s)", env)
+				}
+				vars := mergeVars(cfg, target, a.Language, env, a.Artifact.TargetVars(target))
+				for _, problem := range verifyVars(cfg, target, a.Language, env, vars) {
+					result.AddError("%s: %s", owner, problem)
+					varErrors++
+				}
+			}
+		}
+	}
+	if varErrors == 0 {
+		p.Printf("%s all valid\n", p.green("✓"))
+	} else {
+		p.Printf("%s %d invalid\n", p.red("✗"), varErrors)
+	}
+
+	// 8. Check for circular dependencies
 	p.Printf("  Checking for cycles... ")
 	cycles := findCycles(artifacts)
 	if len(cycles) > 0 {
@@ -156,6 +261,96 @@ func Check(configPath string) error {
 	return printCheckResult(p, result)
 }
 
+// checkArtifactTargets checks that an artifact has either a target or a
+// list of distinct, known targets
+func checkArtifactTargets(result *ValidationResult, a *config.Artifact, targetNames map[string]bool) {
+	if a.Target != "" && len(a.Targets) > 0 {
+		result.AddError("Artifact '%s' sets both target and targets", a.Name)
+		return
+	}
+	targets := a.TargetNames()
+	if len(targets) == 0 {
+		result.AddError("Artifact '%s' has no target defined", a.Name)
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		switch {
+		case target == "":
+			result.AddError("Artifact '%s' has a target without a name", a.Name)
+		case seen[target]:
+			result.AddError("Artifact '%s' lists target '%s' more than once", a.Name, target)
+		case !targetNames[target]:
+			result.AddError("Artifact '%s' references unknown target '%s'", a.Name, target)
+		}
+		seen[target] = true
+	}
+}
+
+// definitionName names a definition for messages, with the file it came
+// from if that is not the main config, e.g. "Target 'docker' (ci/targets.yml)"
+func definitionName(cfg *config.Config, configPath, kind, label, name string) string {
+	owner := fmt.Sprintf("%s '%s'", label, name)
+	if source := cfg.Source(kind, name); source != "" && source != filepath.Base(configPath) {
+		owner += " (" + source + ")"
+	}
+	return owner
+}
+
+// checkSteps validates the if, timeout and retries settings of steps
+func checkSteps(result *ValidationResult, owner string, steps []config.Step) {
+	for _, step := range steps {
+		if step.If != "" {
+			if err := checkCondition(step.If); err != nil {
+				result.AddError("%s step '%s' has an invalid if: %v", owner, step.Name, err)
+			}
+		}
+		if _, err := step.TimeoutDuration(); err != nil {
+			result.AddError("%s step '%s' has an %v", owner, step.Name, err)
+		}
+		if step.Retries < 0 {
+			result.AddError("%s step '%s' has negative retries", owner, step.Name)
+		}
+	}
+}
+
+// checkDeclarations validates the var declarations of a language or target
+func checkDeclarations(result *ValidationResult, owner string, decls map[string]config.VarDecl) {
+	for _, name := range sortedKeys(decls) {
+		if !isValidOutputName(name) {
+			result.AddError("%s declares '%s', which is not a valid variable name", owner, name)
+		}
+		if err := decls[name].Validate(); err != nil {
+			result.AddError("%s var '%s': %v", owner, name, err)
+		}
+	}
+}
+
+// checkTemplates reports syntax errors and unknown names in the templates
+// of vars and steps, and env() of secrets. Values only known at plan time
+// are not checked.
+func checkTemplates(result *ValidationResult, cfg *config.Config, owner string, vars map[string]string, steps []config.Step) {
+	values := make(templateValues, len(templateBuiltins))
+	for name := range templateBuiltins {
+		values[name] = name
+	}
+	_, _, err := renderVarsAndSteps(vars, steps, values, secretEnvNames(cfg))
+	if err != nil && !errors.Is(err, errUnresolved) {
+		result.AddError("%s %v", owner, err)
+	}
+}
+
+// checkSecret validates the name and source of a secret
+func checkSecret(result *ValidationResult, owner, name string, secret config.Secret) {
+	if !isValidOutputName(name) {
+		result.AddError("%s '%s' is not a valid variable name", owner, name)
+	}
+	if err := secret.Validate(); err != nil {
+		result.AddError("%s '%s': %v", owner, name, err)
+	}
+}
+
 // findCycles finds circular dependencies
 func findCycles(artifacts []internal.DiscoveredArtifact) [][]string {
 	var cycles [][]string
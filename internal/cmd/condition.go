@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// conditionEnv provides the values a step condition can refer to
+type conditionEnv struct {
+	lookup  func(name string) string     // Resolves $VAR (vars, then process env)
+	changed func(patterns []string) bool // Reports whether files matching a pattern changed
+}
+
+// evalCondition evaluates the `if` expression of a step.
+//
+// Supported syntax:
+//
+//	$VAR, ${VAR}                 value of a var or environment variable
+//	'text', "text"               string literal
+//	true, false                  boolean literal
+//	changed('glob', ...)         any changed file matches one of the globs
+//	a == b, a != b               string comparison
+//	!x, x && y, x || y, ( x )    boolean logic
+//
+// A value is true unless it is empty, "false" or "0".
+func evalCondition(expr string, env conditionEnv) (bool, error) {
+	p := &condParser{env: env}
+	if err := p.tokenize(expr); err != nil {
+		return false, err
+	}
+	if len(p.tokens) == 0 {
+		return true, nil
+	}
+
+	v, err := p.parseOr()
+	if err != nil {
+		return false, err
+	}
+	if p.pos < len(p.tokens) {
+		return false, fmt.Errorf("unexpected '%s'", p.tokens[p.pos].text)
+	}
+	return truthy(v), nil
+}
+
+// checkCondition reports syntax errors in a condition without evaluating
+// variables or changed files
+func checkCondition(expr string) error {
+	_, err := evalCondition(expr, conditionEnv{
+		lookup:  func(string) string { return "" },
+		changed: func([]string) bool { return false },
+	})
+	return err
+}
+
+func truthy(v string) bool {
+	return v != "" && v != "false" && v != "0"
+}
+
+func boolValue(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+type condTokenKind int
+
+const (
+	tokOp     condTokenKind = iota // ==, !=, &&, ||, !, (, ), ","
+	tokString                      // Quoted literal
+	tokVar                         // $VAR
+	tokIdent                       // true, false, function names
+)
+
+type condToken struct {
+	kind condTokenKind
+	text string
+}
+
+type condParser struct {
+	env    conditionEnv
+	tokens []condToken
+	pos    int
+}
+
+func (p *condParser) tokenize(expr string) error {
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case strings.HasPrefix(expr[i:], "==") || strings.HasPrefix(expr[i:], "!=") ||
+			strings.HasPrefix(expr[i:], "&&") || strings.HasPrefix(expr[i:], "||"):
+			p.tokens = append(p.tokens, condToken{tokOp, expr[i : i+2]})
+			i += 2
+		case c == '!' || c == '(' || c == ')' || c == ',':
+			p.tokens = append(p.tokens, condToken{tokOp, string(c)})
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			p.tokens = append(p.tokens, condToken{tokString, expr[i+1 : i+1+end]})
+			i += end + 2
+		case c == '$':
+			name, n := scanVarName(expr[i+1:])
+			if name == "" {
+				return fmt.Errorf("invalid variable reference at position %d", i+1)
+			}
+			p.tokens = append(p.tokens, condToken{tokVar, name})
+			i += 1 + n
+		case isIdentChar(rune(c)):
+			j := i
+			for j < len(expr) && isIdentChar(rune(expr[j])) {
+				j++
+			}
+			p.tokens = append(p.tokens, condToken{tokIdent, expr[i:j]})
+			i = j
+		default:
+			return fmt.Errorf("unexpected character '%c' at position %d", c, i+1)
+		}
+	}
+	return nil
+}
+
+// scanVarName reads a variable name after '$', either NAME or {NAME}.
+// It returns the name and the number of bytes consumed.
+func scanVarName(s string) (string, int) {
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", 0
+		}
+		return s[1:end], end + 1
+	}
+	n := 0
+	for n < len(s) && isIdentChar(rune(s[n])) {
+		n++
+	}
+	return s[:n], n
+}
+
+func isIdentChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (p *condParser) peek() condToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return condToken{}
+}
+
+func (p *condParser) acceptOp(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op && p.pos < len(p.tokens) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *condParser) parseOr() (string, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return "", err
+	}
+	for p.acceptOp("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return "", err
+		}
+		left = boolValue(truthy(left) || truthy(right))
+	}
+	return left, nil
+}
+
+func (p *condParser) parseAnd() (string, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return "", err
+	}
+	for p.acceptOp("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		left = boolValue(truthy(left) && truthy(right))
+	}
+	return left, nil
+}
+
+func (p *condParser) parseUnary() (string, error) {
+	if p.acceptOp("!") {
+		v, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		return boolValue(!truthy(v)), nil
+	}
+	return p.parseComparison()
+}
+
+func (p *condParser) parseComparison() (string, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case p.acceptOp("=="):
+		right, err := p.parseOperand()
+		if err != nil {
+			return "", err
+		}
+		return boolValue(left == right), nil
+	case p.acceptOp("!="):
+		right, err := p.parseOperand()
+		if err != nil {
+			return "", err
+		}
+		return boolValue(left != right), nil
+	}
+	return left, nil
+}
+
+func (p *condParser) parseOperand() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", fmt.Errorf("unexpected end of condition")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+
+	switch t.kind {
+	case tokString:
+		return t.text, nil
+	case tokVar:
+		return p.env.lookup(t.text), nil
+	case tokOp:
+		if t.text == "(" {
+			v, err := p.parseOr()
+			if err != nil {
+				return "", err
+			}
+			if !p.acceptOp(")") {
+				return "", fmt.Errorf("missing ')'")
+			}
+			return v, nil
+		}
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return t.text, nil
+		case "changed":
+			args, err := p.parseArgs()
+			if err != nil {
+				return "", fmt.Errorf("changed: %w", err)
+			}
+			if len(args) == 0 {
+				return "", fmt.Errorf("changed: expected at least one pattern")
+			}
+			return boolValue(p.env.changed(args)), nil
+		}
+		return "", fmt.Errorf("unknown identifier '%s'", t.text)
+	}
+	return "", fmt.Errorf("unexpected '%s'", t.text)
+}
+
+// parseArgs parses a parenthesized list of string literals
+func (p *condParser) parseArgs() ([]string, error) {
+	if !p.acceptOp("(") {
+		return nil, fmt.Errorf("expected '('")
+	}
+	var args []string
+	if p.acceptOp(")") {
+		return args, nil
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokString || p.pos >= len(p.tokens) {
+			return nil, fmt.Errorf("expected a quoted pattern")
+		}
+		p.pos++
+		args = append(args, t.text)
+
+		if p.acceptOp(")") {
+			return args, nil
+		}
+		if !p.acceptOp(",") {
+			return nil, fmt.Errorf("expected ',' or ')'")
+		}
+	}
+}
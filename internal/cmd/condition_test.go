@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestEvalCondition(t *testing.T) {
+	vars := map[string]string{"BRANCH": "main", "DRY_RUN": "false", "REGION": "eu"}
+	env := conditionEnv{
+		lookup: func(name string) string { return vars[name] },
+		changed: func(patterns []string) bool {
+			for _, p := range patterns {
+				if p == "migrations/**" {
+					return true
+				}
+			}
+			return false
+		},
+	}
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{"", true},
+		{"true", true},
+		{"false", false},
+		{"$BRANCH == 'main'", true},
+		{"${BRANCH} != \"main\"", false},
+		{"$DRY_RUN", false},
+		{"!$DRY_RUN", true},
+		{"$MISSING", false},
+		{"$BRANCH == 'main' && $REGION == 'us'", false},
+		{"$BRANCH == 'main' && ($REGION == 'us' || $REGION == 'eu')", true},
+		{"changed('migrations/**')", true},
+		{"changed('*.md', 'docs/**')", false},
+		{"!changed('*.md') && $BRANCH == 'main'", true},
+	}
+
+	for _, tt := range tests {
+		got, err := evalCondition(tt.expr, env)
+		if err != nil {
+			t.Errorf("evalCondition(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("evalCondition(%q) = %v, expected %v", tt.expr, got, tt.expected)
+		}
+	}
+}
+
+func TestCheckCondition_Errors(t *testing.T) {
+	tests := []string{
+		"$BRANCH ==",
+		"'unterminated",
+		"($A == 'x'",
+		"changed()",
+		"changed($A)",
+		"unknown('x')",
+		"$A == 'x' 'y'",
+		"$A = 'x'",
+	}
+
+	for _, expr := range tests {
+		if err := checkCondition(expr); err == nil {
+			t.Errorf("expected error for '%s'", expr)
+		}
+	}
+}
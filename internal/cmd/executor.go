@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Executor runs the commands of steps. Implementations decide where and
+// how a command runs, e.g. in a local shell, a sandbox or on a remote runner.
+type Executor interface {
+	// Execute runs a step command and writes its output to req.Stdout and
+	// req.Stderr. A non-nil error marks the step as failed.
+	Execute(ctx context.Context, req StepRequest) error
+}
+
+// StepRequest describes a single execution of a step command
+type StepRequest struct {
+	Artifact string            // Artifact the step belongs to
+	Step     string            // Step name
+	Run      string            // Command to run
+	WorkDir  string            // Artifact directory
+	Vars     map[string]string // Vars, passed as environment variables
+	Stdin    io.Reader         // Input of the command, nil for none
+	Stdout   io.Writer
+	Stderr   io.Writer
+}
+
+// dryRunner is implemented by executors that only pretend to run steps.
+// Plan and apply don't persist anything for dry runs.
+type dryRunner interface {
+	DryRun() bool
+}
+
+// isDryRun reports whether an executor only pretends to run steps
+func isDryRun(e Executor) bool {
+	d, ok := e.(dryRunner)
+	return ok && d.DryRun()
+}
+
+// ShellExecutor runs steps in the local shell (sh -c, cmd /C on Windows)
+type ShellExecutor struct{}
+
+// Execute runs the step command in a local shell
+func (ShellExecutor) Execute(ctx context.Context, req StepRequest) error {
+	return runShell(ctx, req)
+}
+
+// RecordingExecutor records every step request and passes it on to Next.
+// Without Next it is a dry run: commands are printed instead of executed.
+type RecordingExecutor struct {
+	Next Executor
+
+	mu       sync.Mutex
+	requests []StepRequest
+}
+
+// NewDryRunExecutor returns an executor that prints commands without running them
+func NewDryRunExecutor() *RecordingExecutor {
+	return &RecordingExecutor{}
+}
+
+// Execute records the request and runs it with Next, if set
+func (r *RecordingExecutor) Execute(ctx context.Context, req StepRequest) error {
+	r.mu.Lock()
+	r.requests = append(r.requests, req)
+	r.mu.Unlock()
+
+	if r.Next == nil {
+		fmt.Fprintf(req.Stdout, "[dry-run] %s\n", strings.TrimSpace(req.Run))
+		return nil
+	}
+	return r.Next.Execute(ctx, req)
+}
+
+// Requests returns the recorded requests in execution order
+func (r *RecordingExecutor) Requests() []StepRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]StepRequest(nil), r.requests...)
+}
+
+// DryRun reports whether commands are only printed
+func (r *RecordingExecutor) DryRun() bool {
+	return r.Next == nil
+}
+
+var (
+	executorsMu sync.Mutex
+	executors   = map[string]func() Executor{
+		"shell":   func() Executor { return ShellExecutor{} },
+		"dry-run": func() Executor { return NewDryRunExecutor() },
+	}
+)
+
+// RegisterExecutor makes an executor available under name, for use in the
+// executor setting of bear.config.yml and the --executor flag
+func RegisterExecutor(name string, factory func() Executor) {
+	executorsMu.Lock()
+	defer executorsMu.Unlock()
+	executors[name] = factory
+}
+
+// newExecutor creates the executor registered under name ("" = shell)
+func newExecutor(name string) (Executor, error) {
+	if name == "" {
+		name = "shell"
+	}
+
+	executorsMu.Lock()
+	defer executorsMu.Unlock()
+
+	factory, ok := executors[name]
+	if !ok {
+		var names []string
+		for n := range executors {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown executor: %s (available: %s)", name, strings.Join(names, ", "))
+	}
+	return factory(), nil
+}
+
+// resolveExecutor returns the executor for a run. The --executor flag takes
+// precedence over the executor setting in the config.
+func resolveExecutor(configured string, opts Options) (Executor, error) {
+	if opts.StepExecutor != nil {
+		return opts.StepExecutor, nil
+	}
+	if opts.Executor != "" {
+		return newExecutor(opts.Executor)
+	}
+	return newExecutor(configured)
+}
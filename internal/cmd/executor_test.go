@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNewExecutor(t *testing.T) {
+	e, err := newExecutor("")
+	if err != nil {
+		t.Fatalf("newExecutor failed: %v", err)
+	}
+	if _, ok := e.(ShellExecutor); !ok {
+		t.Errorf("expected ShellExecutor by default, got %T", e)
+	}
+
+	e, err = newExecutor("dry-run")
+	if err != nil {
+		t.Fatalf("newExecutor failed: %v", err)
+	}
+	if !isDryRun(e) {
+		t.Error("expected dry-run executor")
+	}
+
+	if _, err := newExecutor("sandbox"); err == nil || !strings.Contains(err.Error(), "available: dry-run, shell") {
+		t.Errorf("expected unknown executor error, got %v", err)
+	}
+
+	fake := &FakeExecutor{}
+	RegisterExecutor("sandbox", func() Executor { return fake })
+	e, err = newExecutor("sandbox")
+	if err != nil || e != fake {
+		t.Errorf("expected registered executor, got %v, %v", e, err)
+	}
+}
+
+func TestResolveExecutor(t *testing.T) {
+	e, err := resolveExecutor("dry-run", Options{})
+	if err != nil || !isDryRun(e) {
+		t.Errorf("expected config executor, got %T, %v", e, err)
+	}
+
+	e, err = resolveExecutor("dry-run", Options{Executor: "shell"})
+	if err != nil || isDryRun(e) {
+		t.Errorf("expected flag to override config, got %T, %v", e, err)
+	}
+
+	fake := &FakeExecutor{}
+	e, err = resolveExecutor("dry-run", Options{Executor: "shell", StepExecutor: fake})
+	if err != nil || e != fake {
+		t.Errorf("expected executor instance to take precedence, got %T, %v", e, err)
+	}
+}
+
+func TestRecordingExecutor(t *testing.T) {
+	fake := &FakeExecutor{Results: map[string]FakeResult{"make": {Stdout: "built\n"}}}
+	rec := &RecordingExecutor{Next: fake}
+
+	var out bytes.Buffer
+	if err := rec.Execute(context.Background(), StepRequest{Artifact: "api", Run: "make", Stdout: &out}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if rec.DryRun() {
+		t.Error("expected recording executor with Next not to be a dry run")
+	}
+	if out.String() != "built\n" || len(fake.Calls()) != 1 || len(rec.Requests()) != 1 {
+		t.Errorf("expected request to be recorded and passed on, got output %q", out.String())
+	}
+
+	dry := NewDryRunExecutor()
+	out.Reset()
+	if err := dry.Execute(context.Background(), StepRequest{Run: "rm -rf build\n", Stdout: &out}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if out.String() != "[dry-run] rm -rf build\n" {
+		t.Errorf("unexpected dry-run output %q", out.String())
+	}
+}
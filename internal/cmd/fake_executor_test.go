@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FakeResult is the outcome of a step run by FakeExecutor
+type FakeResult struct {
+	Stdout  string
+	Err     error
+	Outputs map[string]string // Written to $BEAR_OUTPUT
+}
+
+// FakeExecutor is an in-memory executor for tests. Steps succeed without
+// running anything unless Results has an entry for their command.
+type FakeExecutor struct {
+	Results map[string]FakeResult // Keyed by the step command
+
+	mu    sync.Mutex
+	calls []StepRequest
+}
+
+// Execute records the request and returns the configured result
+func (f *FakeExecutor) Execute(ctx context.Context, req StepRequest) error {
+	f.mu.Lock()
+	f.calls = append(f.calls, req)
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res := f.Results[req.Run]
+	if res.Stdout != "" && req.Stdout != nil {
+		fmt.Fprint(req.Stdout, res.Stdout)
+	}
+	if len(res.Outputs) > 0 {
+		if err := writeFakeOutputs(req.Vars[outputEnv], res.Outputs); err != nil {
+			return err
+		}
+	}
+	return res.Err
+}
+
+// Calls returns the executed requests in execution order
+func (f *FakeExecutor) Calls() []StepRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]StepRequest(nil), f.calls...)
+}
+
+// writeFakeOutputs writes outputs to the $BEAR_OUTPUT file of a step
+func writeFakeOutputs(path string, outputs map[string]string) error {
+	if path == "" {
+		return nil
+	}
+	keys := make([]string, 0, len(outputs))
+	for k := range outputs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, outputs[k])
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+// computeFingerprint hashes the inputs of a plan: the resolved config,
+// the lock file of the environment and the presets in use.
+func computeFingerprint(cfg *config.Config, rootPath, env string) (*config.PlanFingerprint, error) {
+	configHash, err := config.Hash(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing config: %w", err)
+	}
+
+	lockHash, err := config.HashFile(config.LockFilePath(rootPath, env))
+	if err != nil {
+		return nil, fmt.Errorf("error hashing lock file: %w", err)
+	}
+
+	presets := make(map[string]string, len(cfg.Presets))
+	for k, v := range cfg.Presets {
+		presets[k] = v
+	}
+
+	return &config.PlanFingerprint{
+		Config:  configHash,
+		Lock:    lockHash,
+		Presets: presets,
+	}, nil
+}
+
+// planDrift describes every input that changed since the plan was created.
+// An empty result means the plan is up to date.
+func planDrift(planFile *config.PlanFile, current *config.PlanFingerprint, currentCommit, rootPath string) []string {
+	var drift []string
+
+	if currentCommit != "" && planFile.Commit != "" && currentCommit != planFile.Commit {
+		drift = append(drift, fmt.Sprintf("HEAD has moved (plan: %s, current: %s)",
+			planFile.Commit[:min(7, len(planFile.Commit))],
+			currentCommit[:min(7, len(currentCommit))]))
+	}
+
+	planned := planFile.Fingerprint
+	if planned == nil {
+		// Plans without fingerprint can only be checked against HEAD
+		return drift
+	}
+
+	if planned.Config != current.Config {
+		drift = append(drift, "resolved config changed (bear.config.yml or presets)")
+	}
+
+	if planned.Lock != current.Lock {
+		lockName := filepath.Base(config.LockFilePath(rootPath, planFile.Environment))
+		drift = append(drift, fmt.Sprintf("lock file %s changed (another deployment ran since the plan)", lockName))
+	}
+
+	names := make(map[string]bool)
+	for name := range planned.Presets {
+		names[name] = true
+	}
+	for name := range current.Presets {
+		names[name] = true
+	}
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		before, wasUsed := planned.Presets[name]
+		after, isUsed := current.Presets[name]
+		switch {
+		case !wasUsed:
+			drift = append(drift, fmt.Sprintf("preset %s is now used", name))
+		case !isUsed:
+			drift = append(drift, fmt.Sprintf("preset %s is no longer used", name))
+		case before != after:
+			drift = append(drift, fmt.Sprintf("preset %s changed", name))
+		}
+	}
+
+	return drift
+}
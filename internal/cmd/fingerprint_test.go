@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+func TestPlanDrift(t *testing.T) {
+	rootPath := t.TempDir()
+	cfg := &config.Config{
+		Name:    "acme",
+		Targets: map[string]config.Target{"cloudrun": {Vars: map[string]string{"REGION": "europe-west1"}}},
+		Presets: map[string]string{"language/go": "aaa", "target/docker": "bbb"},
+	}
+
+	planned, err := computeFingerprint(cfg, rootPath, "")
+	if err != nil {
+		t.Fatalf("computeFingerprint failed: %v", err)
+	}
+	planFile := &config.PlanFile{Commit: "abc1234567", Fingerprint: planned}
+
+	if drift := planDrift(planFile, planned, "abc1234567", rootPath); len(drift) != 0 {
+		t.Fatalf("expected no drift, got %v", drift)
+	}
+
+	// Change every input
+	cfg.Targets["cloudrun"].Vars["REGION"] = "us-central1"
+	cfg.Presets = map[string]string{"language/go": "changed", "target/lambda": "ccc"}
+	if err := os.WriteFile(config.LockFilePath(rootPath, ""), []byte("artifacts: {}\n"), 0644); err != nil {
+		t.Fatalf("failed to write lock file: %v", err)
+	}
+
+	current, err := computeFingerprint(cfg, rootPath, "")
+	if err != nil {
+		t.Fatalf("computeFingerprint failed: %v", err)
+	}
+	drift := planDrift(planFile, current, "def7890123", rootPath)
+
+	expected := []string{
+		"HEAD has moved",
+		"resolved config changed",
+		"lock file bear.lock.yml changed",
+		"preset language/go changed",
+		"preset target/docker is no longer used",
+		"preset target/lambda is now used",
+	}
+	if len(drift) != len(expected) {
+		t.Fatalf("expected %d drift entries, got %v", len(expected), drift)
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(drift[i], prefix) {
+			t.Errorf("drift %d: expected prefix '%s', got '%s'", i, prefix, drift[i])
+		}
+	}
+}
+
+func TestPlanDrift_WithoutFingerprint(t *testing.T) {
+	planFile := &config.PlanFile{Commit: "abc1234567"}
+	current := &config.PlanFingerprint{Config: "anything"}
+
+	if drift := planDrift(planFile, current, "abc1234567", t.TempDir()); len(drift) != 0 {
+		t.Errorf("expected no drift for legacy plan at same HEAD, got %v", drift)
+	}
+}
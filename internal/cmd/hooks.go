@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+// Hook names
+const (
+	hookPrePlan   = "pre_plan"
+	hookPostPlan  = "post_plan"
+	hookPreApply  = "pre_apply"
+	hookPostApply = "post_apply"
+	hookOnFailure = "on_failure"
+)
+
+// hookSummary describes a plan or apply run. Hooks get it as JSON on stdin
+// and as BEAR_* environment variables.
+type hookSummary struct {
+	Hook        string   `json:"hook"`
+	Project     string   `json:"project"`
+	Environment string   `json:"environment,omitempty"`
+	Commit      string   `json:"commit,omitempty"`
+	Status      string   `json:"status"` // "running" in pre_* hooks, then "success" or "failed"
+	Error       string   `json:"error,omitempty"`
+	Validated   []string `json:"validated,omitempty"`
+	Deploy      []string `json:"deploy,omitempty"` // Planned deployments
+	Deployed    []string `json:"deployed,omitempty"`
+	Failed      []string `json:"failed,omitempty"`
+	Skipped     []string `json:"skipped,omitempty"`
+}
+
+// finish sets the final status of the run
+func (s *hookSummary) finish(err error) {
+	s.Status = "success"
+	if err != nil {
+		s.Status = "failed"
+		s.Error = err.Error()
+	}
+}
+
+// env returns the summary as environment variables. Lists are comma-separated.
+func (s hookSummary) env() map[string]string {
+	return map[string]string{
+		"BEAR_HOOK":        s.Hook,
+		"BEAR_PROJECT":     s.Project,
+		"BEAR_ENVIRONMENT": s.Environment,
+		"BEAR_COMMIT":      s.Commit,
+		"BEAR_STATUS":      s.Status,
+		"BEAR_ERROR":       s.Error,
+		"BEAR_VALIDATED":   strings.Join(s.Validated, ","),
+		"BEAR_DEPLOY":      strings.Join(s.Deploy, ","),
+		"BEAR_DEPLOYED":    strings.Join(s.Deployed, ","),
+		"BEAR_FAILED":      strings.Join(s.Failed, ","),
+		"BEAR_SKIPPED":     strings.Join(s.Skipped, ","),
+	}
+}
+
+// hookRunner runs the hooks of a plan or apply
+type hookRunner struct {
+	p        *Printer
+	executor Executor
+	stream   *Printer
+	rootPath string
+	vars     map[string]string // Environment vars (and secrets during apply)
+	verbose  bool
+}
+
+// run runs the steps of a hook with the summary. It returns an error if a
+// step failed.
+func (h *hookRunner) run(ctx context.Context, name string, steps []config.Step, summary hookSummary) error {
+	if len(steps) == 0 {
+		return nil
+	}
+
+	summary.Hook = name
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	vars := make(map[string]string, len(h.vars))
+	for k, v := range h.vars {
+		vars[k] = v
+	}
+	for k, v := range summary.env() {
+		vars[k] = v
+	}
+
+	scope := newStepScope(h.executor, h.rootPath, name, h.rootPath, vars, nil, false)
+	scope.stream = h.stream
+	scope.stdin = data
+
+	res := runSteps(ctx, steps, scope, h.verbose)
+	if res.err != nil {
+		h.p.FailureWithOutput(fmt.Sprintf("hook %s — %s", name, res.err), res.output)
+		return fmt.Errorf("%s hook failed: %w", name, res.err)
+	}
+	h.p.Success(fmt.Sprintf("%s %s", h.p.dim("hook"), name))
+	printStepWarnings(h.p, res.warnings)
+	if h.verbose && h.stream == nil && res.output != "" {
+		h.p.ErrorBox(res.output)
+	}
+	return nil
+}
+
+// finish runs the post hook and, if the run failed, on_failure. Hook
+// failures are shown but don't change the result of the run. The hooks
+// also run after an interrupt.
+func (h *hookRunner) finish(ctx context.Context, post string, hooks config.Hooks, summary *hookSummary, err error) {
+	ctx = context.WithoutCancel(ctx)
+	summary.finish(err)
+
+	postSteps := hooks.PostPlan
+	if post == hookPostApply {
+		postSteps = hooks.PostApply
+	}
+	h.run(ctx, post, postSteps, *summary)
+	if err != nil {
+		h.run(ctx, hookOnFailure, hooks.OnFailure, *summary)
+	}
+}
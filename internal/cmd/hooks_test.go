@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+func TestHookRunner_Summary(t *testing.T) {
+	fake := &FakeExecutor{}
+	h := &hookRunner{
+		p:        NewPrinterWithWriter(io.Discard),
+		executor: fake,
+		rootPath: t.TempDir(),
+		vars:     map[string]string{"REGION": "eu"},
+	}
+	summary := hookSummary{Project: "shop", Environment: "prod", Deployed: []string{"api", "web"}}
+	summary.finish(errors.New("deployment failed"))
+
+	if err := h.run(context.Background(), hookPostApply, []config.Step{{Name: "Notify", Run: "notify"}}, summary); err != nil {
+		t.Fatalf("hook failed: %v", err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 call, got %d", len(calls))
+	}
+	call := calls[0]
+	if call.WorkDir != h.rootPath {
+		t.Errorf("expected hook to run in the workspace root, got '%s'", call.WorkDir)
+	}
+
+	expectedVars := map[string]string{
+		"REGION":           "eu",
+		"BEAR_HOOK":        "post_apply",
+		"BEAR_ENVIRONMENT": "prod",
+		"BEAR_STATUS":      "failed",
+		"BEAR_ERROR":       "deployment failed",
+		"BEAR_DEPLOYED":    "api,web",
+	}
+	for k, v := range expectedVars {
+		if call.Vars[k] != v {
+			t.Errorf("var %s: expected '%s', got '%s'", k, v, call.Vars[k])
+		}
+	}
+
+	var stdin hookSummary
+	if err := json.NewDecoder(call.Stdin).Decode(&stdin); err != nil {
+		t.Fatalf("expected the summary as JSON on stdin: %v", err)
+	}
+	if stdin.Hook != "post_apply" || stdin.Status != "failed" || len(stdin.Deployed) != 2 {
+		t.Errorf("unexpected summary on stdin: %+v", stdin)
+	}
+}
+
+func TestApplyWithOptions_Hooks(t *testing.T) {
+	content := testConfig + `hooks:
+  pre_apply:
+    - name: Login
+      run: login
+  post_apply:
+    - name: Report
+      run: report
+  on_failure:
+    - name: Alert
+      run: alert
+`
+	artifacts := func(dir string) []config.PlanArtifact {
+		return []config.PlanArtifact{
+			{Name: "web", Path: dir, Target: "ok", Action: "deploy", Steps: []config.Step{{Name: "Deploy", Run: "deploy web"}}},
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		dir := t.TempDir()
+		configPath := writeTestPlanWithConfig(t, dir, content, artifacts(dir))
+		fake := &FakeExecutor{}
+
+		if err := ApplyWithOptions(context.Background(), configPath, Options{NoCommit: true, StepExecutor: fake}); err != nil {
+			t.Fatalf("apply failed: %v", err)
+		}
+		assertRuns(t, fake, "login", "deploy web", "report")
+	})
+
+	t.Run("failing pre_apply aborts", func(t *testing.T) {
+		dir := t.TempDir()
+		configPath := writeTestPlanWithConfig(t, dir, content, artifacts(dir))
+		fake := &FakeExecutor{Results: map[string]FakeResult{"login": {Err: errors.New("exit status 1")}}}
+
+		if err := ApplyWithOptions(context.Background(), configPath, Options{NoCommit: true, StepExecutor: fake}); err == nil {
+			t.Fatal("expected apply to fail")
+		}
+		assertRuns(t, fake, "login", "report", "alert")
+		if _, err := os.Stat(config.LockFilePath(dir, "")); !os.IsNotExist(err) {
+			t.Error("expected no lock file to be written")
+		}
+	})
+}
+
+// assertRuns checks the commands an executor ran, in order
+func assertRuns(t *testing.T, fake *FakeExecutor, expected ...string) {
+	t.Helper()
+	calls := fake.Calls()
+	if len(calls) != len(expected) {
+		var runs []string
+		for _, c := range calls {
+			runs = append(runs, c.Run)
+		}
+		t.Fatalf("expected runs %v, got %v", expected, runs)
+	}
+	for i, run := range expected {
+		if calls[i].Run != run {
+			t.Errorf("run %d: expected '%s', got '%s'", i, run, calls[i].Run)
+		}
+	}
+}
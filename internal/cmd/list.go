@@ -4,19 +4,30 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/irevolve/bear/internal"
+	"github.com/irevolve/bear/internal/config"
 )
 
-func List(configPath string) error {
+func List(configPath string, opts Options) error {
 	p := NewPrinter()
 
-	cfg, err := internal.Load(configPath)
+	sel := opts.selector()
+	if err := sel.Validate(); err != nil {
+		return err
+	}
+
+	cfg, err := internal.Load(configPath, opts.ConfigOverlays)
 	if err != nil {
 		return fmt.Errorf("error loading config: %w", err)
 	}
 
+	if err := checkEnvironment(cfg, opts.Environment); err != nil {
+		return err
+	}
+
 	// Search in the directory of the config file
 	rootPath := filepath.Dir(configPath)
 	if rootPath == "." {
@@ -28,11 +39,19 @@ func List(configPath string) error {
 		return fmt.Errorf("error scanning artifacts: %w", err)
 	}
 
+	artifacts = internal.FilterArtifacts(artifacts, sel)
 	if len(artifacts) == 0 {
+		if !sel.IsEmpty() {
+			p.Printf("No artifacts found matching: %s\n", sel)
+			return nil
+		}
 		p.Println("No artifacts found.")
 		return nil
 	}
 
+	// Load lock file for deployment info
+	lockFile, _ := config.LoadLock(config.LockFilePath(rootPath, opts.Environment))
+
 	p.BearHeader(fmt.Sprintf("List (%d artifacts in %s)", len(artifacts), cfg.Name))
 
 	for _, a := range artifacts {
@@ -46,7 +65,7 @@ func List(configPath string) error {
 		p.Detail("Path:    ", relPath)
 		p.Detail("Language:", a.Language)
 
-		if !a.Artifact.IsLib {
+		if !a.Artifact.IsLib && len(a.Artifact.Targets) == 0 {
 			p.Detail("Target:  ", a.Artifact.Target)
 		}
 
@@ -61,8 +80,54 @@ func List(configPath string) error {
 			p.Detail("Depends: ", strings.Join(a.Artifact.Depends, ", "))
 		}
 
+		if len(a.Artifact.Tags) > 0 {
+			p.Detail("Tags:    ", strings.Join(a.Artifact.Tags, ", "))
+		}
+
+		if lockFile != nil && len(a.Artifact.Targets) == 0 {
+			if entry, ok := lockFile.Artifacts[a.Artifact.Name]; ok {
+				p.Detail("Deployed:", entry.Version)
+				if len(entry.Outputs) > 0 {
+					p.Detail("Outputs: ", "")
+					for _, k := range sortedKeys(entry.Outputs) {
+						p.Printf("               %s\n", p.dim(fmt.Sprintf("%s: %s", k, entry.Outputs[k])))
+					}
+				}
+			}
+		}
+
+		// Artifacts with several targets list vars and deployments per target
+		if !a.Artifact.IsLib && len(a.Artifact.Targets) > 0 {
+			p.Detail("Targets: ", "")
+			for _, t := range a.Artifact.Targets {
+				p.Printf("               %s\n", t.Name)
+				for _, k := range sortedKeys(t.Vars) {
+					p.Printf("                 %s\n", p.dim(fmt.Sprintf("%s: %s", k, t.Vars[k])))
+				}
+				if lockFile == nil {
+					continue
+				}
+				if entry, ok := lockFile.Artifacts[a.Artifact.LockKey(t.Name)]; ok {
+					p.Printf("                 %s\n", p.dim("deployed: "+entry.Version))
+					for _, k := range sortedKeys(entry.Outputs) {
+						p.Printf("                 %s\n", p.dim(fmt.Sprintf("%s: %s", k, entry.Outputs[k])))
+					}
+				}
+			}
+		}
+
 		p.Blank()
 	}
 
 	return nil
 }
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,11 +1,37 @@
 package cmd
 
+import "github.com/irevolve/bear/internal"
+
 // Options contains all options for plan and apply
 type Options struct {
-	Artifacts   []string // Specific artifacts to select
-	PinCommit   string   // Commit to pin artifact(s) to
-	Force       bool     // Ignore pinned artifacts
-	NoCommit    bool     // Disable automatic commit after apply (default: commit enabled)
-	Concurrency int      // Max parallel jobs (default: 10)
-	Verbose     bool     // Show step output even on success
+	Artifacts      []string // Specific artifacts to select
+	Tags           []string // Select artifacts with these tags (any of the expressions)
+	ExcludeTags    []string // Leave out artifacts with these tags
+	WithDeps       bool     // Also select the dependencies of selected artifacts
+	PinCommit      string   // Commit to pin artifact(s) to
+	PinTarget      string   // Only pin this target of the artifacts ("" = all targets)
+	Force          bool     // Ignore pinned artifacts
+	NoCommit       bool     // Disable automatic commit after apply (default: commit enabled)
+	Concurrency    int      // Max parallel jobs (default: 10)
+	Verbose        bool     // Show step output even on success
+	Environment    string   // Environment to plan/apply against ("" = default)
+	ConfigOverlays []string // Config files applied on top of bear.config.yml, in order
+	PromoteFrom    string   // Source environment for promote plans
+	Output         string   // Plan output format: "text" (default), "json" or "yaml"
+	AllowStale     bool     // Apply a plan even if its inputs changed
+	Executor       string   // Step executor by name, overrides the config (e.g. "dry-run")
+	Stream         *bool    // Stream step output live (nil = on with Verbose or without a terminal)
+	NoCache        bool     // Validate again instead of using cached results
+
+	StepExecutor Executor // Step executor instance, takes precedence over Executor
+}
+
+// selector returns the artifact selection of the options
+func (o Options) selector() internal.Selector {
+	return internal.Selector{
+		Names:       o.Artifacts,
+		Tags:        o.Tags,
+		ExcludeTags: o.ExcludeTags,
+		WithDeps:    o.WithDeps,
+	}
 }
@@ -5,34 +5,47 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
 
+	"github.com/irevolve/bear/internal"
 	"golang.org/x/term"
 )
 
 // ANSI color codes
 const (
-	colorReset  = "\033[0m"
-	colorRed    = "\033[31m"
-	colorGreen  = "\033[32m"
-	colorYellow = "\033[33m"
-	colorCyan   = "\033[36m"
-	colorDim    = "\033[2m"
-	colorBold   = "\033[1m"
+	colorReset   = "\033[0m"
+	colorRed     = "\033[31m"
+	colorGreen   = "\033[32m"
+	colorYellow  = "\033[33m"
+	colorBlue    = "\033[34m"
+	colorMagenta = "\033[35m"
+	colorCyan    = "\033[36m"
+	colorDim     = "\033[2m"
+	colorBold    = "\033[1m"
 )
 
+// streamColors are assigned to artifacts in the order their output appears
+var streamColors = []string{colorCyan, colorMagenta, colorBlue, colorYellow, colorGreen}
+
 // Printer handles structured, colored CLI output
 type Printer struct {
 	out   io.Writer
 	color bool
+	tty   bool // Output goes to a terminal
+
+	mu           sync.Mutex        // Serializes streamed lines
+	streamColors map[string]string // Artifact → color of its stream prefix
 }
 
 // NewPrinter creates a new Printer. Colors are enabled when writing to a terminal.
 func NewPrinter() *Printer {
-	p := &Printer{out: os.Stdout}
-	if f, ok := p.out.(*os.File); ok {
-		p.color = term.IsTerminal(int(f.Fd()))
-	}
-	return p
+	return newPrinterForFile(os.Stdout)
+}
+
+// newPrinterForFile creates a Printer writing to f. Colors are enabled when f is a terminal.
+func newPrinterForFile(f *os.File) *Printer {
+	tty := term.IsTerminal(int(f.Fd()))
+	return &Printer{out: f, color: tty, tty: tty}
 }
 
 // NewPrinterWithWriter creates a Printer with a custom writer (colors disabled).
@@ -58,14 +71,19 @@ func (p *Printer) bold(text string) string   { return p.colorize(colorBold, text
 
 // --- Output methods ---
 
+// write prints text with secret values masked
+func (p *Printer) write(text string) {
+	io.WriteString(p.out, internal.Redact(text))
+}
+
 // Println prints a line
 func (p *Printer) Println(a ...interface{}) {
-	fmt.Fprintln(p.out, a...)
+	p.write(fmt.Sprintln(a...))
 }
 
 // Printf prints formatted text
 func (p *Printer) Printf(format string, a ...interface{}) {
-	fmt.Fprintf(p.out, format, a...)
+	p.write(fmt.Sprintf(format, a...))
 }
 
 // Blank prints an empty line
@@ -192,3 +210,25 @@ func (p *Printer) Hint(text string) {
 func (p *Printer) Warning(text string) {
 	p.Printf("  %s %s\n", p.yellow("⚠"), text)
 }
+
+// StreamLine prints a line of live step output, prefixed with the artifact
+// and step name. Each artifact gets its own prefix color. Safe for
+// concurrent use.
+func (p *Printer) StreamLine(artifact, step, line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	prefix := fmt.Sprintf("[%s/%s]", artifact, step)
+	if p.color {
+		if p.streamColors == nil {
+			p.streamColors = make(map[string]string)
+		}
+		color, ok := p.streamColors[artifact]
+		if !ok {
+			color = streamColors[len(p.streamColors)%len(streamColors)]
+			p.streamColors[artifact] = color
+		}
+		prefix = color + prefix + colorReset
+	}
+	p.write(fmt.Sprintf("  %s %s\n", prefix, line))
+}
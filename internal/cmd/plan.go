@@ -1,22 +1,37 @@
 package cmd
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/irevolve/bear/internal"
 	"github.com/irevolve/bear/internal/config"
 )
 
-func PlanWithOptions(configPath string, opts Options) error {
-	ctx := context.Background()
+func PlanWithOptions(ctx context.Context, configPath string, opts Options) (err error) {
+
+	// Machine-readable output goes to stdout, progress to stderr
+	machineOutput := false
+	switch opts.Output {
+	case "", "text":
+	case "json", "yaml":
+		machineOutput = true
+	default:
+		return fmt.Errorf("unknown output format: %s (use text, json or yaml)", opts.Output)
+	}
 	p := NewPrinter()
+	if machineOutput {
+		p = newPrinterForFile(os.Stderr)
+	}
+	if err := opts.selector().Validate(); err != nil {
+		return err
+	}
 
-	cfg, err := internal.Load(configPath)
+	cfg, err := internal.Load(configPath, opts.ConfigOverlays)
 	if err != nil {
 		return fmt.Errorf("error loading config: %w", err)
 	}
@@ -26,10 +41,61 @@ func PlanWithOptions(configPath string, opts Options) error {
 		rootPath, _ = os.Getwd()
 	}
 
+	if err := checkEnvironment(cfg, opts.Environment); err != nil {
+		return err
+	}
+	if opts.PromoteFrom != "" {
+		if err := checkEnvironment(cfg, opts.PromoteFrom); err != nil {
+			return err
+		}
+		if opts.PromoteFrom == opts.Environment {
+			return fmt.Errorf("cannot promote environment '%s' to itself", opts.Environment)
+		}
+	}
+
+	executor, err := resolveExecutor(cfg.Executor, opts)
+	if err != nil {
+		return err
+	}
+	dryRun := isDryRun(executor)
+	var stream *Printer
+	if streamOutput(opts, p) {
+		stream = p
+	}
+
+	unlock, err := acquireStateLock(cfg, rootPath, opts.Environment, "plan")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Hooks run once around the plan, post_plan and on_failure also after errors
+	hooks := &hookRunner{
+		p:        p,
+		executor: executor,
+		stream:   stream,
+		rootPath: rootPath,
+		vars:     mergeVars(cfg, "", "", opts.Environment, nil),
+		verbose:  opts.Verbose || dryRun,
+	}
+	summary := &hookSummary{
+		Project:     cfg.Name,
+		Environment: opts.Environment,
+		Commit:      internal.GetCurrentCommit(rootPath),
+		Status:      "running",
+	}
+	defer func() { hooks.finish(ctx, hookPostPlan, cfg.Hooks, summary, err) }()
+	if err := hooks.run(ctx, hookPrePlan, cfg.Hooks.PrePlan, *summary); err != nil {
+		return err
+	}
+
 	planOpts := internal.PlanOptions{
-		Artifacts: opts.Artifacts,
-		PinCommit: opts.PinCommit,
-		Force:     opts.Force,
+		Selector:    opts.selector(),
+		PinCommit:   opts.PinCommit,
+		PinTarget:   opts.PinTarget,
+		Force:       opts.Force,
+		Environment: opts.Environment,
+		PromoteFrom: opts.PromoteFrom,
 	}
 
 	plan, err := internal.CreatePlanWithOptions(rootPath, cfg, planOpts)
@@ -50,80 +116,145 @@ func PlanWithOptions(configPath string, opts Options) error {
 		}
 	}
 
+	currentCommit := summary.Commit
+	for _, s := range skips {
+		summary.Skipped = append(summary.Skipped, s.Artifact.Artifact.LockKey(s.Target))
+	}
+
 	if len(validates) == 0 && len(deploys) == 0 {
-		if len(opts.Artifacts) > 0 {
-			p.Printf("No artifacts found matching: %v\n", opts.Artifacts)
+		if sel := opts.selector(); !sel.IsEmpty() {
+			p.Printf("No artifacts found matching: %s\n", sel)
 		} else {
 			p.Println("No changes detected. Nothing to plan.")
 		}
+		if machineOutput {
+			// Emit an empty plan so that tooling always gets a document
+			planFile := config.NewPlanFile(currentCommit)
+			planFile.Environment = opts.Environment
+			addSkipped(planFile, skips)
+			return writePlanOutput(planFile, opts.Output)
+		}
 		return nil
 	}
 
-	currentCommit := internal.GetCurrentCommit(rootPath)
 	deployVersion := currentCommit
 	if opts.PinCommit != "" {
 		deployVersion = opts.PinCommit
 	}
 
+	// Vars and templates are resolved and checked before any step runs
+	tmpl := newTemplateSource(rootPath, plan.LockFile)
+	var problems []string
+	seenProblems := make(map[string]bool)
+	prepare := func(action internal.PlannedAction, commit string) artifactInputs {
+		in, err := prepareInputs(cfg, opts.Environment, action, tmpl, commit)
+		found := verifyVars(cfg, action.Target, action.Artifact.Language, opts.Environment, in.vars)
+		if err != nil {
+			found = append([]string{err.Error()}, found...)
+		}
+		for _, problem := range found {
+			problem = fmt.Sprintf("%s: %s", action.Artifact.Artifact.Name, problem)
+			if !seenProblems[problem] {
+				seenProblems[problem] = true
+				problems = append(problems, problem)
+			}
+		}
+		return in
+	}
+	validateInputs := make([]artifactInputs, len(validates))
+	for i, v := range validates {
+		validateInputs[i] = prepare(v, currentCommit)
+	}
+	deployInputs := make([]artifactInputs, len(deploys))
+	for i, d := range deploys {
+		deployInputs[i] = prepare(d, deployCommit(d, deployVersion))
+	}
+	if len(problems) > 0 {
+		p.Printf("  %s\n", p.red("Invalid vars:"))
+		for _, problem := range problems {
+			p.Printf("    %s %s\n", p.red("•"), problem)
+		}
+		p.Hint("Fix the vars above and run 'bear plan' again.")
+		return fmt.Errorf("invalid vars")
+	}
+
 	// Phase 1: Validate all changed artifacts in parallel
+	cached := 0
 	if len(validates) > 0 {
 		p.PhaseHeader(fmt.Sprintf("Validating %d artifact(s)", len(validates)))
 
+		// A dry run validates nothing, so it neither uses nor fills the cache
+		var vcache *validationCache
+		if !dryRun {
+			vcache = newValidationCache(cfg, rootPath, plan.Artifacts, !opts.NoCache)
+		}
+
 		type valResult struct {
-			name   string
-			output string
-			err    error
+			name     string
+			output   string
+			err      error
+			warnings []string
+			cached   bool
 		}
 		results := make([]valResult, len(validates))
 
 		errs := RunParallel(ctx, opts.Concurrency, len(validates), func(ctx context.Context, i int) error {
 			v := validates[i]
-			var combinedOutput bytes.Buffer
-
-			for _, step := range v.Steps {
-				var stdout, stderr bytes.Buffer
-				execErr := ExecuteStep(ctx, step.Run, v.Artifact.Path, mergeVars(cfg, v.Artifact.Artifact.Target, v.Artifact.Language, v.Artifact.Artifact.Vars), &stdout, &stderr)
-
-				if opts.Verbose {
-					combinedOutput.WriteString(fmt.Sprintf("  → %s\n", step.Name))
-					combinedOutput.Write(stdout.Bytes())
-					combinedOutput.Write(stderr.Bytes())
-				}
+			name := v.Artifact.Artifact.Name
+			vars, steps := validateInputs[i].vars, validateInputs[i].steps
 
-				if execErr != nil {
-					combinedOutput.Write(stdout.Bytes())
-					combinedOutput.Write(stderr.Bytes())
-					results[i] = valResult{
-						name:   v.Artifact.Artifact.Name,
-						output: combinedOutput.String(),
-						err:    fmt.Errorf("%s: %w", step.Name, execErr),
-					}
-					return execErr
-				}
+			key := vcache.key(name, steps, vars, v.ChangedFiles, v.ChangesUnknown)
+			if entry := vcache.get(key); entry != nil {
+				results[i] = valResult{name: name, warnings: entry.Warnings, cached: true}
+				return nil
 			}
 
+			scope := newStepScope(executor, rootPath, name, v.Artifact.Path, vars, v.ChangedFiles, v.ChangesUnknown)
+			scope.stream = stream
+
+			res := runSteps(ctx, steps, scope, opts.Verbose || dryRun)
 			results[i] = valResult{
-				name:   v.Artifact.Artifact.Name,
-				output: combinedOutput.String(),
+				name:     name,
+				output:   res.output,
+				err:      res.err,
+				warnings: res.warnings,
 			}
-			return nil
+			if res.err == nil && ctx.Err() == nil {
+				vcache.put(key, name, res.warnings)
+			}
+			return res.err
 		})
 
 		// Print results in order
 		var failures []string
-		for i, res := range results {
-			_ = i
+		for _, res := range results {
 			if res.err != nil {
 				p.FailureWithOutput(fmt.Sprintf("%s — %s", res.name, res.err), res.output)
 				failures = append(failures, res.name)
+				continue
+			}
+			summary.Validated = append(summary.Validated, res.name)
+			if res.cached {
+				p.Success(fmt.Sprintf("%s %s", res.name, p.dim("validated (cached)")))
+				printStepWarnings(p, res.warnings)
+				cached++
 			} else {
 				p.Success(res.name)
-				if opts.Verbose && res.output != "" {
+				printStepWarnings(p, res.warnings)
+				if (opts.Verbose || dryRun) && stream == nil && res.output != "" {
 					p.ErrorBox(res.output)
 				}
 			}
 		}
 
+		summary.Failed = failures
+
+		if ctx.Err() != nil {
+			p.Blank()
+			p.Printf("  %s\n", p.yellow("Interrupted, no plan was written."))
+			return fmt.Errorf("plan interrupted")
+		}
+
 		if len(CollectErrors(errs)) > 0 {
 			p.Blank()
 			p.Printf("  %s\n", p.red(fmt.Sprintf("Validation failed for: %s", strings.Join(failures, ", "))))
@@ -137,26 +268,45 @@ func PlanWithOptions(configPath string, opts Options) error {
 
 	// Phase 2: Write plan file
 	planFile := config.NewPlanFile(currentCommit)
+	planFile.Environment = opts.Environment
 	planFile.Validated = len(validates)
+	planFile.Cached = cached
+	planFile.TotalChanges = plan.TotalChanges
 
-	for _, d := range deploys {
-		vars := mergeVars(cfg, d.Artifact.Artifact.Target, d.Artifact.Language, d.Artifact.Artifact.Vars)
+	fingerprint, err := computeFingerprint(cfg, rootPath, opts.Environment)
+	if err != nil {
+		return err
+	}
+	planFile.Fingerprint = fingerprint
+
+	for i, d := range deploys {
+		version := deployCommit(d, deployVersion)
+		vars := deployInputs[i].vars
 		vars["NAME"] = d.Artifact.Artifact.Name
-		vars["VERSION"] = deployVersion[:min(7, len(deployVersion))]
+		vars["VERSION"] = version[:min(7, len(version))]
 
 		pa := config.PlanArtifact{
-			Name:         d.Artifact.Artifact.Name,
-			Path:         d.Artifact.Path,
-			Language:     d.Artifact.Language,
-			Target:       d.Artifact.Artifact.Target,
-			Action:       "deploy",
-			Reason:       d.Reason,
-			ChangedFiles: d.ChangedFiles,
-			Vars:         vars,
-			Steps:        d.Steps,
-			IsLib:        d.Artifact.Artifact.IsLib,
+			Name:           d.Artifact.Artifact.Name,
+			Path:           d.Artifact.Path,
+			Language:       d.Artifact.Language,
+			Target:         d.Target,
+			Action:         "deploy",
+			Reason:         d.Reason,
+			ChangedFiles:   d.ChangedFiles,
+			ChangesUnknown: d.ChangesUnknown,
+			Depends:        d.Artifact.Artifact.Depends,
+			Vars:           vars,
+			Steps:          deployInputs[i].steps,
+			Commit:         d.Commit,
+			LastCommit:     plan.LockFile.GetLastDeployedCommit(d.Artifact.Artifact.LockKey(d.Target)),
+			TreeHash:       d.TreeHash,
+			Outputs:        cfg.Targets[d.Target].Outputs,
+			IsLib:          d.Artifact.Artifact.IsLib,
 		}
 
+		if key := d.Artifact.Artifact.LockKey(d.Target); key != pa.Name {
+			pa.LockKey = key
+		}
 		if d.PinCommit != "" {
 			pa.Pinned = true
 			pa.PinCommit = d.PinCommit
@@ -164,31 +314,76 @@ func PlanWithOptions(configPath string, opts Options) error {
 
 		planFile.Artifacts = append(planFile.Artifacts, pa)
 		planFile.ToDeploy++
+		summary.Deploy = append(summary.Deploy, pa.Key())
 	}
 
-	for _, s := range skips {
-		planFile.Skipped = append(planFile.Skipped, config.PlanSkipped{
-			Name:   s.Artifact.Artifact.Name,
-			Reason: s.Reason,
-		})
-		planFile.TotalSkips++
-	}
+	addSkipped(planFile, skips)
 
-	if err := config.WritePlan(rootPath, planFile); err != nil {
+	// A dry run did not validate anything, so there is no plan to apply
+	if dryRun {
+		p.Blank()
+		p.Printf("  %s\n", p.yellow("Dry run: steps were not executed, no plan file written."))
+	} else if err := config.WritePlan(rootPath, planFile); err != nil {
 		return fmt.Errorf("error writing plan file: %w", err)
 	}
 
 	// Phase 3: Show the validated plan
-	printValidatedPlan(p, plan, planFile, rootPath, opts)
+	if machineOutput {
+		return writePlanOutput(planFile, opts.Output)
+	}
+	printValidatedPlan(p, planFile, rootPath, opts, !dryRun)
 
 	return nil
 }
 
-func printValidatedPlan(p *Printer, plan *internal.Plan, planFile *config.PlanFile, rootPath string, opts Options) {
+// deployCommit returns the commit an action deploys: the promoted commit or
+// the deploy version of the plan
+func deployCommit(action internal.PlannedAction, deployVersion string) string {
+	if action.Commit != "" {
+		return action.Commit
+	}
+	return deployVersion
+}
+
+// addSkipped adds skipped actions to the plan file
+func addSkipped(planFile *config.PlanFile, skips []internal.PlannedAction) {
+	for _, s := range skips {
+		skipped := config.PlanSkipped{
+			Name:   s.Artifact.Artifact.Name,
+			Reason: s.Reason,
+		}
+		// Name the target if it is one of several
+		if len(s.Artifact.Artifact.Targets) > 0 {
+			skipped.Target = s.Target
+		}
+		planFile.Skipped = append(planFile.Skipped, skipped)
+		planFile.TotalSkips++
+	}
+}
+
+// writePlanOutput writes the plan to stdout in a machine-readable format
+func writePlanOutput(planFile *config.PlanFile, format string) error {
+	data, err := config.EncodePlan(planFile, format)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+func printValidatedPlan(p *Printer, planFile *config.PlanFile, rootPath string, opts Options, applyHint bool) {
 	p.PhaseHeader("Plan")
 
-	if len(opts.Artifacts) > 0 {
-		p.Printf("  Artifacts: %s\n", strings.Join(opts.Artifacts, ", "))
+	if planFile.Environment != "" {
+		p.Printf("  Environment: %s\n", p.bold(planFile.Environment))
+		if opts.PromoteFrom != "" {
+			p.Printf("  Promoting from: %s\n", opts.PromoteFrom)
+		}
+		p.Blank()
+	}
+
+	if sel := opts.selector(); !sel.IsEmpty() {
+		p.Printf("  Artifacts: %s\n", sel)
 		p.Blank()
 	}
 
@@ -197,8 +392,8 @@ func printValidatedPlan(p *Printer, plan *internal.Plan, planFile *config.PlanFi
 		p.Blank()
 	}
 
-	if plan.TotalChanges > 0 {
-		p.Printf("  %s\n", p.dim(fmt.Sprintf("%d file(s) changed", plan.TotalChanges)))
+	if planFile.TotalChanges > 0 {
+		p.Printf("  %s\n", p.dim(fmt.Sprintf("%d file(s) changed", planFile.TotalChanges)))
 		p.Blank()
 	}
 
@@ -213,13 +408,10 @@ func printValidatedPlan(p *Printer, plan *internal.Plan, planFile *config.PlanFi
 			p.Detail("Target:", d.Target)
 			p.Detail("Reason:", d.Reason)
 
-			if plan.LockFile != nil {
-				lastCommit := plan.LockFile.GetLastDeployedCommit(d.Name)
-				if lastCommit != "" {
-					p.Detail("Last:  ", lastCommit[:min(7, len(lastCommit))])
-				} else {
-					p.Detail("Last:  ", "(never deployed)")
-				}
+			if d.LastCommit != "" {
+				p.Detail("Last:  ", d.LastCommit[:min(7, len(d.LastCommit))])
+			} else {
+				p.Detail("Last:  ", "(never deployed)")
 			}
 
 			if len(d.Steps) > 0 {
@@ -241,7 +433,11 @@ func printValidatedPlan(p *Printer, plan *internal.Plan, planFile *config.PlanFi
 			if strings.Contains(s.Reason, "pinned") {
 				reason = " 📌"
 			}
-			p.Printf("  %s %s%s\n", p.dim("–"), p.dim(s.Name), reason)
+			name := s.Name
+			if s.Target != "" {
+				name += " → " + s.Target
+			}
+			p.Printf("  %s %s%s\n", p.dim("–"), p.dim(name), reason)
 		}
 		p.Blank()
 	}
@@ -249,7 +445,11 @@ func printValidatedPlan(p *Printer, plan *internal.Plan, planFile *config.PlanFi
 	// Summary
 	parts := []string{}
 	if planFile.Validated > 0 {
-		parts = append(parts, p.SummaryValidated(planFile.Validated))
+		validated := p.SummaryValidated(planFile.Validated)
+		if planFile.Cached > 0 {
+			validated += " " + p.dim(fmt.Sprintf("(%d cached)", planFile.Cached))
+		}
+		parts = append(parts, validated)
 	}
 	if planFile.ToDeploy > 0 {
 		parts = append(parts, p.SummaryDeploy(planFile.ToDeploy))
@@ -259,32 +459,72 @@ func printValidatedPlan(p *Printer, plan *internal.Plan, planFile *config.PlanFi
 	}
 	p.Summary(parts...)
 
-	if planFile.ToDeploy > 0 {
+	if planFile.ToDeploy > 0 && applyHint {
 		p.Hint("Run 'bear apply' to execute this plan.")
 	}
 }
 
-func mergeVars(cfg *config.Config, targetName string, langName string, artifactVars map[string]string) map[string]string {
+func mergeVars(cfg *config.Config, targetName string, langName string, envName string, artifactVars map[string]string) map[string]string {
 	vars := make(map[string]string)
 
-	// 1. Language vars (lowest priority)
+	// 1. Project vars (lowest priority)
+	for k, v := range cfg.Vars {
+		vars[k] = v
+	}
+
+	// 2. Language vars
 	if lang, ok := cfg.Languages[langName]; ok {
 		for k, v := range lang.Vars {
 			vars[k] = v
 		}
 	}
 
-	// 2. Target vars
+	// 3. Target vars
 	if t, ok := cfg.Targets[targetName]; ok {
 		for k, v := range t.Vars {
 			vars[k] = v
 		}
 	}
 
-	// 3. Artifact vars (highest priority)
+	// 4. Artifact vars
 	for k, v := range artifactVars {
 		vars[k] = v
 	}
 
+	// 5. Environment vars (highest priority)
+	if env, ok := cfg.Environments[envName]; ok {
+		for k, v := range env.Vars {
+			vars[k] = v
+		}
+	}
+
+	// Declared defaults fill the vars that no layer sets
+	for name, d := range declaredVars(cfg, targetName, langName) {
+		if _, ok := vars[name]; !ok && d.Default != "" {
+			vars[name] = d.Default
+		}
+	}
+
 	return vars
 }
+
+// checkEnvironment verifies that an environment is defined in the config.
+// The default environment ("") is always valid.
+func checkEnvironment(cfg *config.Config, env string) error {
+	if env == "" {
+		return nil
+	}
+	if _, ok := cfg.Environments[env]; ok {
+		return nil
+	}
+
+	var names []string
+	for name := range cfg.Environments {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("unknown environment: %s (no environments defined in config)", env)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("unknown environment: %s\nAvailable: %s", env, strings.Join(names, ", "))
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup runs the command in its own process group, so that
+// cancelling it also stops the processes the shell started. The group gets
+// SIGTERM first and SIGKILL after killGracePeriod, even if the shell has
+// exited by then: children that trap SIGTERM would be orphaned otherwise.
+// The process group ID cannot be reused while any member is alive, so the
+// SIGKILL never reaches other processes.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := -cmd.Process.Pid
+		if err := syscall.Kill(pgid, syscall.SIGTERM); err != nil {
+			return err
+		}
+		time.AfterFunc(killGracePeriod, func() {
+			syscall.Kill(pgid, syscall.SIGKILL)
+		})
+		return nil
+	}
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/irevolve/bear/internal"
+	"github.com/irevolve/bear/internal/config"
+)
+
+// Rollback creates a pin plan for an earlier successful commit of an
+// artifact, taken from the deployment history in the lock file. Artifacts
+// with several targets are rolled back per target, given as "name@target".
+func Rollback(ctx context.Context, configPath string, artifactName string, steps int, opts Options) error {
+	p := NewPrinter()
+
+	cfg, err := internal.Load(configPath, opts.ConfigOverlays)
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	if err := checkEnvironment(cfg, opts.Environment); err != nil {
+		return err
+	}
+
+	rootPath := filepath.Dir(configPath)
+	if rootPath == "." {
+		rootPath, _ = os.Getwd()
+	}
+
+	lockFile, err := config.LoadLock(config.LockFilePath(rootPath, opts.Environment))
+	if err != nil {
+		return fmt.Errorf("error loading lock file: %w", err)
+	}
+
+	name, target, _ := strings.Cut(artifactName, "@")
+	artifacts, err := internal.ScanArtifacts(rootPath, cfg)
+	if err != nil {
+		return fmt.Errorf("error scanning artifacts: %w", err)
+	}
+	key, err := rollbackKey(artifacts, name, target)
+	if err != nil {
+		return err
+	}
+
+	entry, err := lockFile.PreviousDeployment(key, steps)
+	if err != nil {
+		return err
+	}
+
+	current := lockFile.GetLastDeployedCommit(key)
+	p.Blank()
+	p.Printf("  Rolling back %s: %s → %s %s\n",
+		p.bold(key),
+		current[:min(7, len(current))],
+		p.bold(entry.Commit[:min(7, len(entry.Commit))]),
+		p.dim(fmt.Sprintf("(deployed %s)", entry.Timestamp)))
+
+	opts.Artifacts = []string{name}
+	opts.PinCommit = entry.Commit
+	opts.PinTarget = target
+
+	return PlanWithOptions(ctx, configPath, opts)
+}
+
+// rollbackKey returns the lock entry of the deploy unit to roll back.
+// Artifacts with several targets need the target.
+func rollbackKey(artifacts []internal.DiscoveredArtifact, name, target string) (string, error) {
+	for _, a := range artifacts {
+		if a.Artifact.Name != name {
+			continue
+		}
+		targets := a.Artifact.TargetNames()
+		if target == "" {
+			if len(targets) > 1 {
+				return "", fmt.Errorf("artifact '%s' has several targets, roll back one of them, e.g. %s@%s", name, name, targets[0])
+			}
+			return a.Artifact.LockKey(target), nil
+		}
+		for _, t := range targets {
+			if t == target {
+				return a.Artifact.LockKey(target), nil
+			}
+		}
+		return "", fmt.Errorf("artifact '%s' has no target '%s'", name, target)
+	}
+	return "", fmt.Errorf("artifact '%s' not found", name)
+}
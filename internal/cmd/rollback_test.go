@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/irevolve/bear/internal"
+	"github.com/irevolve/bear/internal/config"
+)
+
+func TestRollbackKey(t *testing.T) {
+	artifacts := []internal.DiscoveredArtifact{
+		{Artifact: &config.Artifact{Name: "web", Target: "cloudrun"}},
+		{Artifact: &config.Artifact{Name: "api", Targets: []config.ArtifactTarget{{Name: "service"}, {Name: "job"}}}},
+	}
+
+	tests := []struct {
+		arg     string
+		want    string
+		wantErr bool
+	}{
+		{arg: "web", want: "web"},
+		{arg: "web@cloudrun", want: "web"},
+		{arg: "api@job", want: "api@job"},
+		{arg: "api", wantErr: true},
+		{arg: "api@worker", wantErr: true},
+		{arg: "missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			name, target, _ := strings.Cut(tt.arg, "@")
+			got, err := rollbackKey(artifacts, name, target)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got '%s'", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+}
@@ -1,19 +1,27 @@
 package cmd
 
 import (
-	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 )
 
 const defaultConcurrency = 10
 
+// killGracePeriod is how long a cancelled step may take to shut down
+// after SIGTERM before it is killed
+var killGracePeriod = 10 * time.Second
+
+// waitDelay bounds how long a killed step may hold on to its output
+const waitDelay = 5 * time.Second
+
 // StepResult holds the result of a single step execution
 type StepResult struct {
 	StepName string
@@ -110,21 +118,31 @@ func buildEnv(vars map[string]string) []string {
 // ExecuteStep runs a single step command in the given working directory.
 // Variables are passed as environment variables to the shell.
 // Output is written to the provided writers.
-func ExecuteStep(ctx context.Context, stepRun string, workDir string, vars map[string]string, stdout, stderr *bytes.Buffer) error {
+func ExecuteStep(ctx context.Context, stepRun string, workDir string, vars map[string]string, stdout, stderr io.Writer) error {
+	return runShell(ctx, StepRequest{Run: stepRun, WorkDir: workDir, Vars: vars, Stdout: stdout, Stderr: stderr})
+}
+
+// runShell runs the command of a step request in the local shell
+func runShell(ctx context.Context, req StepRequest) error {
 	// Detect shell based on OS
 	shell, shellArg := getShell()
 
-	cmd := exec.CommandContext(ctx, shell, shellArg, stepRun)
-	cmd.Dir = workDir
-	cmd.Env = buildEnv(vars)
-
-	if stdout != nil {
-		cmd.Stdout = stdout
+	cmd := exec.CommandContext(ctx, shell, shellArg, req.Run)
+	cmd.Dir = req.WorkDir
+	cmd.Env = buildEnv(req.Vars)
+	cmd.Stdin = req.Stdin
+	setProcessGroup(cmd)
+	// Don't wait forever for children that keep the output pipes open
+	// after the step was cancelled or timed out
+	cmd.WaitDelay = killGracePeriod + waitDelay
+
+	if req.Stdout != nil {
+		cmd.Stdout = req.Stdout
 	} else {
 		cmd.Stdout = os.Stdout
 	}
-	if stderr != nil {
-		cmd.Stderr = stderr
+	if req.Stderr != nil {
+		cmd.Stderr = req.Stderr
 	} else {
 		cmd.Stderr = os.Stderr
 	}
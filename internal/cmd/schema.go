@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+// Schema writes the JSON Schema of a file type to stdout. With an output
+// directory, the schemas of all file types are written there instead.
+func Schema(fileType, outDir string) error {
+	if outDir == "" {
+		if fileType == "" {
+			return fmt.Errorf("specify a file type (%s) or --out", strings.Join(config.SchemaTypes, ", "))
+		}
+		data, err := marshalSchema(fileType)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	types := config.SchemaTypes
+	if fileType != "" {
+		types = []string{fileType}
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("error creating %s: %w", outDir, err)
+	}
+
+	p := NewPrinter()
+	for _, t := range types {
+		data, err := marshalSchema(t)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(outDir, schemaFileName(t))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("error writing %s: %w", path, err)
+		}
+		p.Printf("%s %s\n", p.green("✓"), path)
+	}
+	return nil
+}
+
+// marshalSchema returns the indented JSON Schema of a file type
+func marshalSchema(fileType string) ([]byte, error) {
+	schema, err := config.JSONSchema(fileType)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// schemaFileName returns the file name of a schema, e.g. "bear.artifact.schema.json"
+func schemaFileName(fileType string) string {
+	return strings.TrimSuffix(config.SchemaFileName(fileType), ".yml") + ".schema.json"
+}
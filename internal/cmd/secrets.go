@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/irevolve/bear/internal"
+	"github.com/irevolve/bear/internal/config"
+)
+
+// secretDefinitions returns the secrets of an environment: the project
+// secrets, overridden by the secrets of the environment
+func secretDefinitions(cfg *config.Config, env string) map[string]config.Secret {
+	defs := make(map[string]config.Secret, len(cfg.Secrets))
+	for name, s := range cfg.Secrets {
+		defs[name] = s
+	}
+	if e, ok := cfg.Environments[env]; ok {
+		for name, s := range e.Secrets {
+			defs[name] = s
+		}
+	}
+	return defs
+}
+
+// secretEnvNames maps the environment variables that hold secrets in the
+// project or in any environment to the names of the secrets
+func secretEnvNames(cfg *config.Config) map[string]string {
+	names := make(map[string]string)
+	add := func(secrets map[string]config.Secret) {
+		for name, s := range secrets {
+			if s.Env != "" {
+				names[s.Env] = name
+			}
+		}
+	}
+	add(cfg.Secrets)
+	for _, env := range cfg.Environments {
+		add(env.Secrets)
+	}
+	return names
+}
+
+// resolveSecrets reads the values of all secrets of an environment and
+// registers them for masking. All unresolvable secrets are reported at once.
+func resolveSecrets(cfg *config.Config, rootPath, env string) (map[string]string, error) {
+	defs := secretDefinitions(cfg, env)
+	values := make(map[string]string, len(defs))
+	var problems []string
+	for _, name := range sortedKeys(defs) {
+		value, err := defs[name].Resolve(rootPath)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		values[name] = value
+	}
+
+	masked := make([]string, 0, len(values))
+	for _, v := range values {
+		masked = append(masked, v)
+	}
+	internal.MaskSecrets(masked...)
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("cannot resolve secrets:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return values, nil
+}
+
+// withSecrets returns a copy of vars with the secrets added. Secrets take
+// precedence over vars of the same name.
+func withSecrets(vars, secrets map[string]string) map[string]string {
+	if len(secrets) == 0 {
+		return vars
+	}
+	merged := make(map[string]string, len(vars)+len(secrets))
+	for k, v := range vars {
+		merged[k] = v
+	}
+	for k, v := range secrets {
+		merged[k] = v
+	}
+	return merged
+}
+
+// redactOutputs masks secret values in step outputs before they are recorded
+func redactOutputs(outputs map[string]string) map[string]string {
+	for k, v := range outputs {
+		outputs[k] = internal.Redact(v)
+	}
+	return outputs
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/irevolve/bear/internal"
+	"github.com/irevolve/bear/internal/config"
+)
+
+func TestResolveSecrets_EnvironmentOverride(t *testing.T) {
+	defer internal.ResetSecrets()
+	t.Setenv("BEAR_TEST_DEFAULT_DB", "default-pass")
+	t.Setenv("BEAR_TEST_PROD_DB", "prod-pass")
+
+	cfg := &config.Config{
+		Secrets: map[string]config.Secret{"DB_PASSWORD": {Env: "BEAR_TEST_DEFAULT_DB"}},
+		Environments: map[string]config.Environment{
+			"prod": {Secrets: map[string]config.Secret{"DB_PASSWORD": {Env: "BEAR_TEST_PROD_DB"}}},
+		},
+	}
+
+	for env, expected := range map[string]string{"": "default-pass", "staging": "default-pass", "prod": "prod-pass"} {
+		secrets, err := resolveSecrets(cfg, t.TempDir(), env)
+		if err != nil {
+			t.Fatalf("resolveSecrets(%s) failed: %v", env, err)
+		}
+		if secrets["DB_PASSWORD"] != expected {
+			t.Errorf("env '%s': expected '%s', got '%s'", env, expected, secrets["DB_PASSWORD"])
+		}
+	}
+
+	if got := internal.Redact("prod-pass default-pass"); got != "*** ***" {
+		t.Errorf("expected resolved secrets to be masked, got '%s'", got)
+	}
+}
+
+func TestPrinter_MasksSecrets(t *testing.T) {
+	defer internal.ResetSecrets()
+	internal.MaskSecrets("s3cret")
+
+	var out bytes.Buffer
+	p := NewPrinterWithWriter(&out)
+	p.StreamLine("api", "Deploy", "login with s3cret")
+	p.FailureWithOutput("api", "token: s3cret\n")
+
+	if strings.Contains(out.String(), "s3cret") {
+		t.Errorf("expected secret to be masked, got %q", out.String())
+	}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+// Show renders a saved plan file as text, JSON, YAML or Markdown
+func Show(rootPath string, planPath string, format string) error {
+	if planPath == "" {
+		planPath = config.PlanFilePath(rootPath)
+	}
+
+	planFile, err := config.ReadPlanFile(planPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no plan found at %s. Run 'bear plan' first", planPath)
+		}
+		return fmt.Errorf("error reading plan file: %w", err)
+	}
+
+	switch format {
+	case "", "text":
+		printValidatedPlan(NewPrinter(), planFile, rootPath, Options{}, true)
+		return nil
+	case "json", "yaml":
+		return writePlanOutput(planFile, format)
+	case "markdown", "md":
+		return writePlanMarkdown(os.Stdout, planFile, rootPath)
+	default:
+		return fmt.Errorf("unknown output format: %s (use text, json, yaml or markdown)", format)
+	}
+}
+
+// writePlanMarkdown renders a plan as Markdown, e.g. for pull request comments
+func writePlanMarkdown(w io.Writer, planFile *config.PlanFile, rootPath string) error {
+	var sb strings.Builder
+
+	sb.WriteString("## Bear Plan\n\n")
+
+	commit := planFile.Commit[:min(7, len(planFile.Commit))]
+	sb.WriteString(fmt.Sprintf("**Commit:** `%s`", commit))
+	if planFile.Environment != "" {
+		sb.WriteString(fmt.Sprintf(" · **Environment:** %s", planFile.Environment))
+	}
+	sb.WriteString(fmt.Sprintf(" · **Created:** %s\n\n", planFile.CreatedAt))
+
+	if len(planFile.Artifacts) > 0 {
+		sb.WriteString(fmt.Sprintf("### To Deploy (%d)\n\n", len(planFile.Artifacts)))
+		sb.WriteString("| Artifact | Path | Target | Reason | Last Deployed | Steps |\n")
+		sb.WriteString("|----------|------|--------|--------|---------------|-------|\n")
+		for _, d := range planFile.Artifacts {
+			relPath, err := filepath.Rel(rootPath, d.Path)
+			if err != nil {
+				relPath = d.Path
+			}
+
+			last := "(never deployed)"
+			if d.LastCommit != "" {
+				last = "`" + d.LastCommit[:min(7, len(d.LastCommit))] + "`"
+			}
+
+			var steps []string
+			for _, step := range d.Steps {
+				steps = append(steps, step.Name)
+			}
+
+			sb.WriteString(fmt.Sprintf("| **%s** | `%s` | %s | %s | %s | %s |\n",
+				markdownEscape(d.Name), relPath, markdownEscape(d.Target), markdownEscape(d.Reason),
+				last, markdownEscape(strings.Join(steps, ", "))))
+		}
+		sb.WriteString("\n")
+	} else {
+		sb.WriteString("No artifacts to deploy.\n\n")
+	}
+
+	if len(planFile.Skipped) > 0 {
+		sb.WriteString(fmt.Sprintf("<details>\n<summary>Unchanged (%d)</summary>\n\n", len(planFile.Skipped)))
+		for _, s := range planFile.Skipped {
+			name := s.Name
+			if s.Target != "" {
+				name += " → " + s.Target
+			}
+			sb.WriteString(fmt.Sprintf("- %s — %s\n", markdownEscape(name), markdownEscape(s.Reason)))
+		}
+		sb.WriteString("\n</details>\n\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("**Summary:** %d validated · %d to deploy · %d skipped\n",
+		planFile.Validated, planFile.ToDeploy, planFile.TotalSkips))
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// markdownEscape escapes characters that would break a Markdown table cell
+func markdownEscape(text string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(text)
+}
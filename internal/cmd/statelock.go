@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/irevolve/bear/internal"
+	"github.com/irevolve/bear/internal/config"
+	"github.com/irevolve/bear/internal/state"
+)
+
+// acquireStateLock takes the state lock of an environment for an operation.
+// The returned function releases the lock again.
+func acquireStateLock(cfg *config.Config, rootPath, env, operation string) (func(), error) {
+	backend, err := state.New(cfg.StateLock, rootPath, cfg.Name, env)
+	if err != nil {
+		return nil, err
+	}
+	if backend == nil {
+		return func() {}, nil
+	}
+
+	ttl, err := state.ParseTTL(cfg.StateLock)
+	if err != nil {
+		return nil, err
+	}
+
+	info := state.NewLockInfo(operation, env, ttl)
+	if err := backend.Lock(info); err != nil {
+		return nil, fmt.Errorf("error acquiring state lock: %w\nIf no other run is active, use 'bear force-unlock' to remove it", err)
+	}
+	internal.Debug("acquired state lock", "id", info.ID, "operation", operation)
+
+	return func() {
+		if info.Expired() {
+			internal.Warn("state lock expired before the run finished; set state_lock.ttl longer than the longest apply", "ttl", ttl)
+		}
+		if err := backend.Unlock(info.ID); err != nil {
+			internal.Warn("failed to release state lock", "id", info.ID, "error", err)
+		}
+	}, nil
+}
+
+// ForceUnlock removes the state lock of an environment regardless of its holder
+func ForceUnlock(configPath string, opts Options) error {
+	p := NewPrinter()
+
+	cfg, err := internal.LoadLocal(configPath, opts.ConfigOverlays)
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	if err := checkEnvironment(cfg, opts.Environment); err != nil {
+		return err
+	}
+
+	rootPath := filepath.Dir(configPath)
+	if rootPath == "." {
+		rootPath, _ = os.Getwd()
+	}
+
+	backend, err := state.New(cfg.StateLock, rootPath, cfg.Name, opts.Environment)
+	if err != nil {
+		return err
+	}
+	if backend == nil {
+		p.Println("State locking is disabled.")
+		return nil
+	}
+
+	info, err := backend.Info()
+	if err != nil {
+		return fmt.Errorf("error reading state lock: %w", err)
+	}
+	if info == nil {
+		p.Println("State is not locked.")
+		return nil
+	}
+
+	if err := backend.ForceUnlock(); err != nil {
+		return fmt.Errorf("error removing state lock: %w", err)
+	}
+
+	p.Success(fmt.Sprintf("Removed state lock held by %s", info))
+	return nil
+}
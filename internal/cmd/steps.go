@@ -0,0 +1,327 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/irevolve/bear/internal"
+	"github.com/irevolve/bear/internal/config"
+)
+
+// retryBackoff is the wait before the first retry. It doubles with every
+// further attempt, up to maxRetryBackoff.
+var retryBackoff = time.Second
+
+const maxRetryBackoff = 30 * time.Second
+
+// stepScope describes what the steps of one artifact run against
+type stepScope struct {
+	executor       Executor
+	artifact       string // Artifact name
+	workDir        string
+	vars           map[string]string
+	relPath        string // Artifact directory, relative to the workspace root
+	changedFiles   []config.AffectedFile
+	changesUnknown bool     // changed() matches every pattern
+	stream         *Printer // Streams output lines live if set
+	stdin          []byte   // Input of every step command, nil for none
+}
+
+// stepsResult holds the outcome of running the steps of one artifact
+type stepsResult struct {
+	output   string
+	err      error
+	warnings []string          // Failures of steps with continue_on_error
+	outputs  map[string]string // Values written to $BEAR_OUTPUT by the steps
+}
+
+// errCancelled is returned for steps stopped by an interrupt
+var errCancelled = errors.New("cancelled")
+
+// outputEnv names the env var with the file that steps write outputs to
+const outputEnv = "BEAR_OUTPUT"
+
+// runSteps runs steps sequentially, honoring if, timeout, retries and
+// continue_on_error. It stops at the first failing step. Outputs of a step
+// are available as vars to all later steps.
+func runSteps(ctx context.Context, steps []config.Step, scope stepScope, verbose bool) stepsResult {
+	res := stepsResult{outputs: make(map[string]string)}
+	var combinedOutput bytes.Buffer
+
+	// Outputs are added to a copy of the vars
+	vars := make(map[string]string, len(scope.vars))
+	for k, v := range scope.vars {
+		vars[k] = v
+	}
+	scope.vars = vars
+	cond := scope.conditionEnv()
+
+	for _, step := range steps {
+		if step.If != "" {
+			ok, err := evalCondition(step.If, cond)
+			if err != nil {
+				res.err = fmt.Errorf("%s: invalid if: %w", step.Name, err)
+				break
+			}
+			if !ok {
+				if verbose {
+					combinedOutput.WriteString(fmt.Sprintf("  → %s (skipped: %s)\n", step.Name, step.If))
+				}
+				continue
+			}
+		}
+
+		output, outputs, execErr := runStep(ctx, step, scope)
+		if len(outputs) > 0 {
+			for k, v := range outputs {
+				res.outputs[k] = v
+				vars[k] = v
+			}
+			cond = scope.conditionEnv()
+		}
+
+		if verbose {
+			combinedOutput.WriteString(fmt.Sprintf("  → %s\n", step.Name))
+			combinedOutput.WriteString(output)
+		}
+
+		if execErr != nil {
+			if !verbose {
+				combinedOutput.WriteString(output)
+			}
+			if step.ContinueOnError && ctx.Err() == nil {
+				res.warnings = append(res.warnings, fmt.Sprintf("%s: %v (continue_on_error)", step.Name, execErr))
+				continue
+			}
+			res.err = fmt.Errorf("%s: %w", step.Name, execErr)
+			break
+		}
+	}
+
+	res.output = combinedOutput.String()
+	return res
+}
+
+// runStep runs a single step with its timeout, retrying failed attempts
+// with exponential backoff. It returns the output of all attempts and the
+// outputs the last attempt wrote to $BEAR_OUTPUT.
+func runStep(ctx context.Context, step config.Step, scope stepScope) (string, map[string]string, error) {
+	timeout, err := step.TimeoutDuration()
+	if err != nil {
+		return "", nil, err
+	}
+
+	outputFile, err := os.CreateTemp("", "bear-output-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("error creating output file: %w", err)
+	}
+	outputFile.Close()
+	defer os.Remove(outputFile.Name())
+
+	vars := make(map[string]string, len(scope.vars)+1)
+	for k, v := range scope.vars {
+		vars[k] = v
+	}
+	vars[outputEnv] = outputFile.Name()
+	scope.vars = vars
+
+	var output bytes.Buffer
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			output.WriteString(fmt.Sprintf("  ↻ retry %d/%d\n", attempt, step.Retries))
+			if scope.stream != nil {
+				scope.stream.StreamLine(scope.artifact, step.Name, fmt.Sprintf("↻ retry %d/%d", attempt, step.Retries))
+			}
+			if err := os.Truncate(outputFile.Name(), 0); err != nil {
+				return output.String(), nil, err
+			}
+		}
+
+		err = runAttempt(ctx, step, timeout, scope, &output)
+		if err == nil || attempt >= step.Retries || ctx.Err() != nil {
+			break
+		}
+
+		internal.Debug("step failed, retrying", "step", step.Name, "attempt", attempt+1, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return output.String(), nil, err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+
+	data, readErr := os.ReadFile(outputFile.Name())
+	if readErr != nil {
+		return output.String(), nil, readErr
+	}
+	outputs, parseErr := parseOutputs(string(data))
+	if err == nil && parseErr != nil {
+		err = parseErr
+	}
+	return output.String(), outputs, err
+}
+
+// parseOutputs parses KEY=VALUE lines written to $BEAR_OUTPUT. Empty lines
+// and lines starting with # are ignored, later lines override earlier ones.
+func parseOutputs(data string) (map[string]string, error) {
+	outputs := make(map[string]string)
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !isValidOutputName(key) {
+			return nil, fmt.Errorf("invalid output on line %d of $%s: %q (expected KEY=VALUE)", i+1, outputEnv, line)
+		}
+		outputs[key] = value
+	}
+	return outputs, nil
+}
+
+// isValidOutputName reports whether name can be used as an environment variable
+func isValidOutputName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && !(r >= 'A' && r <= 'Z') && !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// selectOutputs returns the outputs listed in names
+func selectOutputs(outputs map[string]string, names []string) map[string]string {
+	selected := make(map[string]string)
+	for _, name := range names {
+		if v, ok := outputs[name]; ok {
+			selected[name] = v
+		}
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+	return selected
+}
+
+// runAttempt executes a step command once, bounded by timeout (0 = none)
+func runAttempt(ctx context.Context, step config.Step, timeout time.Duration, scope stepScope, output *bytes.Buffer) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	var stdoutW, stderrW io.Writer = &stdout, &stderr
+	if scope.stream != nil {
+		emit := func(line string) { scope.stream.StreamLine(scope.artifact, step.Name, line) }
+		stdoutLines, stderrLines := newLineWriter(emit), newLineWriter(emit)
+		defer stdoutLines.Flush()
+		defer stderrLines.Flush()
+		stdoutW = io.MultiWriter(&stdout, stdoutLines)
+		stderrW = io.MultiWriter(&stderr, stderrLines)
+	}
+
+	req := StepRequest{
+		Artifact: scope.artifact,
+		Step:     step.Name,
+		Run:      step.Run,
+		WorkDir:  scope.workDir,
+		Vars:     scope.vars,
+		Stdout:   stdoutW,
+		Stderr:   stderrW,
+	}
+	if scope.stdin != nil {
+		req.Stdin = bytes.NewReader(scope.stdin)
+	}
+	err := scope.executor.Execute(ctx, req)
+	output.Write(stdout.Bytes())
+	output.Write(stderr.Bytes())
+
+	if err != nil {
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return fmt.Errorf("timed out after %s", timeout)
+		case errors.Is(ctx.Err(), context.Canceled):
+			return errCancelled
+		}
+	}
+	return err
+}
+
+// conditionEnv returns the values available to step conditions
+func (s stepScope) conditionEnv() conditionEnv {
+	resolved := resolveVars(s.vars)
+	return conditionEnv{
+		lookup: func(name string) string {
+			if v, ok := resolved[name]; ok {
+				return v
+			}
+			return os.Getenv(name)
+		},
+		changed: s.changed,
+	}
+}
+
+// changed reports whether a changed file matches one of the patterns.
+// Patterns are relative to the artifact directory. If the changed files
+// are unknown (new artifact, dependency changed, pin, ...), every pattern
+// matches.
+func (s stepScope) changed(patterns []string) bool {
+	if s.changesUnknown {
+		return true
+	}
+	for _, f := range s.changedFiles {
+		rel := f.Path
+		if s.relPath != "." && s.relPath != "" {
+			r, err := filepath.Rel(filepath.FromSlash(s.relPath), filepath.FromSlash(f.Path))
+			if err != nil {
+				continue
+			}
+			rel = filepath.ToSlash(r)
+		}
+		for _, pattern := range patterns {
+			if internal.MatchGlob(pattern, rel) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newStepScope builds the step scope of an artifact located at path
+func newStepScope(executor Executor, rootPath, name, path string, vars map[string]string, changedFiles []config.AffectedFile, changesUnknown bool) stepScope {
+	relPath, err := filepath.Rel(rootPath, path)
+	if err != nil {
+		relPath = path
+	}
+	return stepScope{
+		executor:       executor,
+		artifact:       name,
+		workDir:        path,
+		vars:           vars,
+		relPath:        filepath.ToSlash(relPath),
+		changedFiles:   changedFiles,
+		changesUnknown: changesUnknown,
+	}
+}
+
+// printStepWarnings prints the steps that failed with continue_on_error
+func printStepWarnings(p *Printer, warnings []string) {
+	for _, w := range warnings {
+		p.Printf("    %s %s\n", p.yellow("⚠"), w)
+	}
+}
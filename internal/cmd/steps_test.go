@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+func TestRunSteps(t *testing.T) {
+	if isWindows() {
+		t.Skip("requires a POSIX shell")
+	}
+	defer func(d time.Duration) { retryBackoff = d }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	dir := t.TempDir()
+	scope := stepScope{
+		executor:     ShellExecutor{},
+		workDir:      dir,
+		vars:         map[string]string{"ENV": "prod"},
+		relPath:      "services/api",
+		changedFiles: []config.AffectedFile{{Path: "services/api/main.go"}},
+	}
+
+	t.Run("if skips steps", func(t *testing.T) {
+		res := runSteps(context.Background(), []config.Step{
+			{Name: "Skipped", Run: "exit 1", If: "$ENV == 'dev'"},
+			{Name: "Docs", Run: "exit 1", If: "changed('**/*.md')"},
+			{Name: "Code", Run: "echo code", If: "changed('**/*.go')"},
+		}, scope, true)
+
+		if res.err != nil {
+			t.Fatalf("expected success, got %v", res.err)
+		}
+		if !strings.Contains(res.output, "Skipped (skipped") || !strings.Contains(res.output, "code") {
+			t.Errorf("unexpected output: %s", res.output)
+		}
+	})
+
+	t.Run("retries until success", func(t *testing.T) {
+		res := runSteps(context.Background(), []config.Step{
+			{Name: "Flaky", Run: "echo x >> attempts; [ $(wc -l < attempts) -ge 3 ]", Retries: 2},
+		}, scope, false)
+
+		if res.err != nil {
+			t.Fatalf("expected success after retries, got %v", res.err)
+		}
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		res := runSteps(context.Background(), []config.Step{
+			{Name: "Broken", Run: "echo fail; exit 1", Retries: 1},
+			{Name: "Never", Run: "echo never"},
+		}, scope, false)
+
+		if res.err == nil || !strings.HasPrefix(res.err.Error(), "Broken:") {
+			t.Fatalf("expected Broken to fail, got %v", res.err)
+		}
+		if strings.Count(res.output, "fail") != 2 || strings.Contains(res.output, "never") {
+			t.Errorf("unexpected output: %s", res.output)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		start := time.Now()
+		res := runSteps(context.Background(), []config.Step{
+			{Name: "Slow", Run: "sleep 5", Timeout: "100ms"},
+		}, scope, false)
+
+		if res.err == nil || !strings.Contains(res.err.Error(), "timed out after 100ms") {
+			t.Fatalf("expected timeout, got %v", res.err)
+		}
+		if time.Since(start) > 4*time.Second {
+			t.Error("timeout did not stop the step")
+		}
+	})
+
+	t.Run("continue on error", func(t *testing.T) {
+		res := runSteps(context.Background(), []config.Step{
+			{Name: "Optional", Run: "exit 3", ContinueOnError: true},
+			{Name: "Required", Run: "true"},
+		}, scope, false)
+
+		if res.err != nil {
+			t.Fatalf("expected success, got %v", res.err)
+		}
+		if len(res.warnings) != 1 || !strings.HasPrefix(res.warnings[0], "Optional:") {
+			t.Errorf("expected one warning for Optional, got %v", res.warnings)
+		}
+	})
+}
+
+func TestRunSteps_Outputs(t *testing.T) {
+	if isWindows() {
+		t.Skip("requires a POSIX shell")
+	}
+	defer func(d time.Duration) { retryBackoff = d }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	scope := stepScope{executor: ShellExecutor{}, workDir: t.TempDir(), vars: map[string]string{"NAME": "api"}}
+	res := runSteps(context.Background(), []config.Step{
+		{Name: "Build", Run: `echo "DIGEST=sha256:$NAME" >> "$BEAR_OUTPUT"`},
+		{Name: "Flaky", Run: `echo "ATTEMPT=x" >> "$BEAR_OUTPUT"; [ -f done ] || { touch done; exit 1; }`, Retries: 1},
+		{Name: "Deploy", Run: `echo "URL=https://$NAME/$DIGEST" >> "$BEAR_OUTPUT"`, If: "$DIGEST != ''"},
+	}, scope, false)
+
+	if res.err != nil {
+		t.Fatalf("expected success, got %v", res.err)
+	}
+	expected := map[string]string{
+		"DIGEST":  "sha256:api",
+		"ATTEMPT": "x",
+		"URL":     "https://api/sha256:api",
+	}
+	if len(res.outputs) != len(expected) {
+		t.Fatalf("expected outputs %v, got %v", expected, res.outputs)
+	}
+	for k, v := range expected {
+		if res.outputs[k] != v {
+			t.Errorf("output %s: expected '%s', got '%s'", k, v, res.outputs[k])
+		}
+	}
+	if _, ok := scope.vars["DIGEST"]; ok {
+		t.Error("outputs must not leak into the caller's vars")
+	}
+}
+
+func TestParseOutputs(t *testing.T) {
+	outputs, err := parseOutputs("# comment\nURL=https://x?a=b\n\nDIGEST=1\r\nDIGEST=2\n")
+	if err != nil {
+		t.Fatalf("parseOutputs failed: %v", err)
+	}
+	if outputs["URL"] != "https://x?a=b" || outputs["DIGEST"] != "2" || len(outputs) != 2 {
+		t.Errorf("unexpected outputs: %v", outputs)
+	}
+
+	for _, invalid := range []string{"no separator", "1KEY=x", "MY-KEY=x"} {
+		if _, err := parseOutputs(invalid); err == nil {
+			t.Errorf("expected error for '%s'", invalid)
+		}
+	}
+}
+
+func TestSelectOutputs(t *testing.T) {
+	outputs := map[string]string{"URL": "u", "DIGEST": "d", "TMP": "t"}
+
+	selected := selectOutputs(outputs, []string{"URL", "DIGEST", "MISSING"})
+	if len(selected) != 2 || selected["URL"] != "u" || selected["DIGEST"] != "d" {
+		t.Errorf("unexpected selection: %v", selected)
+	}
+	if selected := selectOutputs(outputs, nil); selected != nil {
+		t.Errorf("expected nil, got %v", selected)
+	}
+}
+
+func TestStepScopeChanged(t *testing.T) {
+	scope := stepScope{
+		relPath: "services/api",
+		changedFiles: []config.AffectedFile{
+			{Path: "services/api/migrations/001.sql"},
+			{Path: "services/api/docs/notes (old)"},
+			{Path: "proto/user.proto", Reason: "watch: ../../proto"},
+		},
+	}
+
+	tests := []struct {
+		pattern  string
+		expected bool
+	}{
+		{"migrations/**", true},
+		{"*.go", false},
+		{"../../proto/*.proto", true},
+		{"docs/notes (old)", true},
+	}
+	for _, tt := range tests {
+		if got := scope.changed([]string{tt.pattern}); got != tt.expected {
+			t.Errorf("changed(%q) = %v, expected %v", tt.pattern, got, tt.expected)
+		}
+	}
+
+	// Unknown changes match every pattern
+	scope.changesUnknown = true
+	if !scope.changed([]string{"*.md"}) {
+		t.Error("expected unknown changes to match every pattern")
+	}
+}
+
+func TestRunSteps_CancelTerminatesProcessGroup(t *testing.T) {
+	if isWindows() {
+		t.Skip("requires a POSIX shell")
+	}
+	defer func(d time.Duration) { killGracePeriod = d }(killGracePeriod)
+	killGracePeriod = 200 * time.Millisecond
+
+	tests := []struct {
+		name string
+		run  string
+	}{
+		// The shell and its child ignore SIGTERM
+		{"stubborn shell", "trap '' TERM; sleep 30 & wait; sleep 30"},
+		// The shell exits on SIGTERM, a detached grandchild ignores it
+		{"orphaned grandchild", `sh -c 'trap "" TERM; sleep 1; touch survived' >/dev/null 2>&1 & sleep 30`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			scope := stepScope{executor: ShellExecutor{}, workDir: dir, vars: map[string]string{}}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(100*time.Millisecond, cancel)
+
+			start := time.Now()
+			res := runSteps(ctx, []config.Step{{Name: "Stubborn", Run: tt.run}}, scope, false)
+
+			if !errors.Is(res.err, errCancelled) {
+				t.Fatalf("expected step to be cancelled, got %v", res.err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("expected the process group to be killed after the grace period, took %s", elapsed)
+			}
+
+			// Processes that outlive the shell are killed after the grace period as well
+			time.Sleep(1500 * time.Millisecond)
+			if _, err := os.Stat(filepath.Join(dir, "survived")); err == nil {
+				t.Error("expected every process of the group to be killed")
+			}
+		})
+	}
+}
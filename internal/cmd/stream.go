@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+)
+
+// lineWriter passes every complete line written to it on to emit
+type lineWriter struct {
+	emit func(line string)
+	buf  []byte
+}
+
+func newLineWriter(emit func(line string)) *lineWriter {
+	return &lineWriter{emit: emit}
+}
+
+// Write emits all complete lines and keeps a trailing partial line
+func (w *lineWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(strings.TrimSuffix(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush emits a trailing partial line
+func (w *lineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.emit(strings.TrimSuffix(string(w.buf), "\r"))
+		w.buf = nil
+	}
+}
+
+// streamOutput reports whether step output is streamed live. Unless set
+// explicitly, streaming is on in verbose mode and when the output is not
+// a terminal (e.g. in CI).
+func streamOutput(opts Options, p *Printer) bool {
+	if opts.Stream != nil {
+		return *opts.Stream
+	}
+	return opts.Verbose || !p.tty
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := newLineWriter(func(line string) { lines = append(lines, line) })
+
+	w.Write([]byte("first\nsec"))
+	w.Write([]byte("ond\r\nthi"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 complete lines, got %v", lines)
+	}
+	w.Flush()
+	w.Flush()
+
+	expected := []string{"first", "second", "thi"}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d: expected '%s', got '%s'", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestPrinterStreamLine_Colors(t *testing.T) {
+	var out bytes.Buffer
+	p := &Printer{out: &out, color: true}
+
+	p.StreamLine("api", "Build", "one")
+	p.StreamLine("web", "Build", "two")
+	p.StreamLine("api", "Push", "three")
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", out.String())
+	}
+	if !strings.HasPrefix(lines[0], "  "+streamColors[0]+"[api/Build]") {
+		t.Errorf("expected first artifact in first color, got %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "  "+streamColors[1]+"[web/Build]") {
+		t.Errorf("expected second artifact in second color, got %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "  "+streamColors[0]+"[api/Push]") {
+		t.Errorf("expected artifact to keep its color, got %q", lines[2])
+	}
+}
+
+func TestStreamOutput(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name     string
+		opts     Options
+		tty      bool
+		expected bool
+	}{
+		{"terminal", Options{}, true, false},
+		{"terminal verbose", Options{Verbose: true}, true, true},
+		{"no terminal", Options{}, false, true},
+		{"explicitly on", Options{Stream: &on}, true, true},
+		{"explicitly off", Options{Stream: &off, Verbose: true}, false, false},
+	}
+
+	for _, tt := range tests {
+		if got := streamOutput(tt.opts, &Printer{tty: tt.tty}); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestRunSteps_Stream(t *testing.T) {
+	var out bytes.Buffer
+	fake := &FakeExecutor{Results: map[string]FakeResult{
+		"build": {Stdout: "compiling\ndone"},
+	}}
+	scope := stepScope{
+		executor: fake,
+		artifact: "api",
+		vars:     map[string]string{},
+		stream:   NewPrinterWithWriter(&out),
+	}
+
+	res := runSteps(context.Background(), []config.Step{{Name: "Build", Run: "build"}}, scope, false)
+	if res.err != nil {
+		t.Fatalf("runSteps failed: %v", res.err)
+	}
+
+	expected := "  [api/Build] compiling\n  [api/Build] done\n"
+	if out.String() != expected {
+		t.Errorf("expected streamed output %q, got %q", expected, out.String())
+	}
+}
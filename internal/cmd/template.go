@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/irevolve/bear/internal"
+	"github.com/irevolve/bear/internal/config"
+)
+
+// Template delimiters. Expressions are only evaluated if templates are
+// enabled in the config, otherwise the text is passed on unchanged.
+const (
+	templateOpen  = "${{"
+	templateClose = "}}"
+)
+
+// templateBuiltins are the values a template can refer to, see templateValues
+var templateBuiltins = map[string]bool{
+	"name":            true,
+	"path":            true,
+	"branch":          true,
+	"sha":             true,
+	"short_sha":       true,
+	"commit_time":     true,
+	"deployed_commit": true,
+	"deps":            true,
+}
+
+// errUnresolved marks references without a value. default() replaces them.
+var errUnresolved = errors.New("unresolved")
+
+// templateValues holds the built-in values of one artifact. Missing or
+// empty values are unresolved.
+type templateValues map[string]string
+
+// renderTemplate evaluates all ${{ ... }} expressions in s.
+//
+// Supported syntax:
+//
+//	name, path, branch, sha, ...      built-in value
+//	'text', "text"                    string literal
+//	lower(x), upper(x)                change case
+//	replace(x, 'old', 'new')          replace all occurrences
+//	default(x, 'fallback')            fallback if x is unresolved or empty
+//	env('NAME')                       environment variable at plan time
+//
+// env() fails for the variables in secretEnv, which hold secret values:
+// the plan file would contain them in plaintext.
+func renderTemplate(s string, values templateValues, secretEnv map[string]string) (string, error) {
+	var out strings.Builder
+	for {
+		start := strings.Index(s, templateOpen)
+		if start < 0 {
+			out.WriteString(s)
+			return out.String(), nil
+		}
+		end := strings.Index(s[start:], templateClose)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated %s", templateOpen)
+		}
+
+		expr := s[start+len(templateOpen) : start+end]
+		value, err := evalTemplate(expr, values, secretEnv)
+		if err != nil {
+			return "", fmt.Errorf("%s%s%s: %w", templateOpen, expr, templateClose, err)
+		}
+		out.WriteString(s[:start])
+		out.WriteString(value)
+		s = s[start+end+len(templateClose):]
+	}
+}
+
+// evalTemplate evaluates a single template expression
+func evalTemplate(expr string, values templateValues, secretEnv map[string]string) (string, error) {
+	p := &templateParser{values: values, secretEnv: secretEnv}
+	if err := p.tokenize(expr); err != nil {
+		return "", err
+	}
+	if len(p.tokens) == 0 {
+		return "", fmt.Errorf("empty expression")
+	}
+
+	v, err := p.parseExpr()
+	if err != nil {
+		return "", err
+	}
+	if p.pos < len(p.tokens) {
+		return "", fmt.Errorf("unexpected '%s'", p.tokens[p.pos].text)
+	}
+	return v, nil
+}
+
+// templateParser evaluates expressions from the tokens of a condition
+type templateParser struct {
+	condParser
+	values    templateValues
+	secretEnv map[string]string // Variables that env() must not read, with their secret
+}
+
+func (p *templateParser) parseExpr() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", fmt.Errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+
+	switch t.kind {
+	case tokString:
+		return t.text, nil
+	case tokVar:
+		return "", fmt.Errorf("$%s is expanded by the shell, use env('%s') for environment variables", t.text, t.text)
+	case tokIdent:
+		if p.peek().kind == tokOp && p.peek().text == "(" {
+			return p.parseCall(t.text)
+		}
+		if !templateBuiltins[t.text] {
+			return "", fmt.Errorf("unknown value '%s'", t.text)
+		}
+		if v := p.values[t.text]; v != "" {
+			return v, nil
+		}
+		return "", fmt.Errorf("%w: %s is not available", errUnresolved, t.text)
+	}
+	return "", fmt.Errorf("unexpected '%s'", t.text)
+}
+
+// templateFuncs maps function names to their number of arguments
+var templateFuncs = map[string]int{"lower": 1, "upper": 1, "replace": 3, "default": 2, "env": 1}
+
+// parseCall evaluates a function call. Unresolved arguments fail the
+// call, except for the first argument of default.
+func (p *templateParser) parseCall(name string) (string, error) {
+	p.pos++ // (
+	var args []string
+	var argErrs []error
+	for !p.acceptOp(")") {
+		if len(args) > 0 && !p.acceptOp(",") {
+			return "", fmt.Errorf("%s: expected ',' or ')'", name)
+		}
+		v, err := p.parseExpr()
+		if err != nil && !errors.Is(err, errUnresolved) {
+			return "", err
+		}
+		args = append(args, v)
+		argErrs = append(argErrs, err)
+	}
+
+	n, ok := templateFuncs[name]
+	if !ok {
+		return "", fmt.Errorf("unknown function '%s'", name)
+	}
+	if len(args) != n {
+		return "", fmt.Errorf("%s: expected %d argument(s), got %d", name, n, len(args))
+	}
+	if name == "default" {
+		if args[0] != "" {
+			return args[0], nil
+		}
+		return args[1], argErrs[1]
+	}
+	for _, err := range argErrs {
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch name {
+	case "lower":
+		return strings.ToLower(args[0]), nil
+	case "upper":
+		return strings.ToUpper(args[0]), nil
+	case "replace":
+		return strings.ReplaceAll(args[0], args[1], args[2]), nil
+	default: // env
+		if secret, ok := p.secretEnv[args[0]]; ok {
+			return "", fmt.Errorf("env('%s') reads secret %s, use $%s so that only bear apply sets the value", args[0], secret, secret)
+		}
+		if v := os.Getenv(args[0]); v != "" {
+			return v, nil
+		}
+		return "", fmt.Errorf("%w: environment variable %s is not set", errUnresolved, args[0])
+	}
+}
+
+// templateSource provides the built-in values of the artifacts of a plan
+type templateSource struct {
+	rootPath string
+	branch   string
+	lock     *config.LockFile
+	times    map[string]string // Commit times by commit
+}
+
+func newTemplateSource(rootPath string, lock *config.LockFile) *templateSource {
+	return &templateSource{
+		rootPath: rootPath,
+		branch:   internal.GetCurrentBranch(rootPath),
+		lock:     lock,
+		times:    make(map[string]string),
+	}
+}
+
+// values returns the built-in values of an artifact built from commit and
+// deployed to target
+func (s *templateSource) values(a internal.DiscoveredArtifact, target, commit string) templateValues {
+	relPath, err := filepath.Rel(s.rootPath, a.Path)
+	if err != nil {
+		relPath = a.Path
+	}
+
+	commitTime, ok := s.times[commit]
+	if !ok && commit != "" {
+		commitTime = internal.GetCommitTime(s.rootPath, commit)
+		s.times[commit] = commitTime
+	}
+
+	values := templateValues{
+		"name":        a.Artifact.Name,
+		"path":        filepath.ToSlash(relPath),
+		"branch":      s.branch,
+		"sha":         commit,
+		"short_sha":   commit[:min(7, len(commit))],
+		"commit_time": commitTime,
+		"deps":        strings.Join(a.Artifact.Depends, ","),
+	}
+	if s.lock != nil {
+		values["deployed_commit"] = s.lock.GetLastDeployedCommit(a.Artifact.LockKey(target))
+	}
+	return values
+}
+
+// renderVarsAndSteps evaluates the templates in vars and step commands.
+// The inputs are not modified.
+func renderVarsAndSteps(vars map[string]string, steps []config.Step, values templateValues, secretEnv map[string]string) (map[string]string, []config.Step, error) {
+	rendered := make(map[string]string, len(vars))
+	for _, name := range sortedKeys(vars) {
+		v, err := renderTemplate(vars[name], values, secretEnv)
+		if err != nil {
+			return nil, nil, fmt.Errorf("var %s: %w", name, err)
+		}
+		rendered[name] = v
+	}
+
+	renderedSteps := make([]config.Step, len(steps))
+	for i, step := range steps {
+		run, err := renderTemplate(step.Run, values, secretEnv)
+		if err != nil {
+			return nil, nil, fmt.Errorf("step '%s': %w", step.Name, err)
+		}
+		step.Run = run
+		renderedSteps[i] = step
+	}
+	return rendered, renderedSteps, nil
+}
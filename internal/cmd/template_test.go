@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/irevolve/bear/internal"
+	"github.com/irevolve/bear/internal/config"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	t.Setenv("BEAR_TEST_REGISTRY", "ghcr.io/acme")
+
+	values := templateValues{
+		"name":      "user-api",
+		"branch":    "feature/Login",
+		"sha":       "0123456789abcdef",
+		"short_sha": "0123456",
+		"deps":      "db,common",
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"no templates $NAME", "no templates $NAME"},
+		{"${{ name }}:${{short_sha}}", "user-api:0123456"},
+		{"${{ lower(replace(branch, '/', '-')) }}", "feature-login"},
+		{"${{ upper('eu') }}", "EU"},
+		{"${{ default(deployed_commit, 'none') }}", "none"},
+		{"${{ default(sha, 'none') }}", "0123456789abcdef"},
+		{"${{ default(env('BEAR_TEST_UNSET'), env('BEAR_TEST_REGISTRY')) }}/app", "ghcr.io/acme/app"},
+		{"deps=${{ deps }}", "deps=db,common"},
+	}
+
+	for _, tt := range tests {
+		got, err := renderTemplate(tt.input, values, nil)
+		if err != nil {
+			t.Errorf("renderTemplate(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("renderTemplate(%q) = '%s', expected '%s'", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestRenderTemplate_Errors(t *testing.T) {
+	values := templateValues{"name": "user-api"}
+
+	tests := []struct {
+		input      string
+		want       string
+		unresolved bool
+	}{
+		{"${{ deployed_commit }}", "deployed_commit is not available", true},
+		{"${{ env('BEAR_TEST_UNSET') }}", "environment variable BEAR_TEST_UNSET is not set", true},
+		{"${{ lower(branch) }}", "branch is not available", true},
+		{"${{ default(branch, commit_time) }}", "commit_time is not available", true},
+		{"${{ version }}", "unknown value 'version'", false},
+		{"${{ trim(name) }}", "unknown function 'trim'", false},
+		{"${{ replace(name, '-') }}", "replace: expected 3 argument(s), got 2", false},
+		{"${{ $NAME }}", "use env('NAME')", false},
+		{"${{ name", "unterminated ${{", false},
+		{"${{ }}", "empty expression", false},
+	}
+
+	for _, tt := range tests {
+		_, err := renderTemplate(tt.input, values, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("renderTemplate(%q): expected error containing '%s', got %v", tt.input, tt.want, err)
+			continue
+		}
+		if errors.Is(err, errUnresolved) != tt.unresolved {
+			t.Errorf("renderTemplate(%q): expected unresolved to be %v, got %v", tt.input, tt.unresolved, err)
+		}
+	}
+}
+
+func TestRenderVarsAndSteps(t *testing.T) {
+	vars := map[string]string{"TAG": "${{ short_sha }}", "REGION": "eu"}
+	steps := []config.Step{{Name: "Push", Run: "docker push app:${{ short_sha }}", If: "$REGION == 'eu'"}}
+
+	rendered, renderedSteps, err := renderVarsAndSteps(vars, steps, templateValues{"short_sha": "abc1234"}, nil)
+	if err != nil {
+		t.Fatalf("renderVarsAndSteps failed: %v", err)
+	}
+	if rendered["TAG"] != "abc1234" || rendered["REGION"] != "eu" {
+		t.Errorf("unexpected vars: %v", rendered)
+	}
+	if renderedSteps[0].Run != "docker push app:abc1234" || renderedSteps[0].If != steps[0].If {
+		t.Errorf("unexpected step: %+v", renderedSteps[0])
+	}
+	if vars["TAG"] != "${{ short_sha }}" || steps[0].Run != "docker push app:${{ short_sha }}" {
+		t.Error("expected inputs to be unchanged")
+	}
+
+	_, _, err = renderVarsAndSteps(map[string]string{"TAG": "${{ sha }}"}, nil, templateValues{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "var TAG") {
+		t.Errorf("expected error naming the var, got %v", err)
+	}
+}
+
+func TestPlanWithOptions_SecretsNotInPlan(t *testing.T) {
+	defer internal.ResetSecrets()
+	t.Setenv("BEAR_TEST_DB_PASSWORD", "s3cret-password")
+
+	tests := []struct {
+		name    string
+		run     string
+		wantErr string
+	}{
+		{name: "shell expansion", run: "deploy --password $DB_PASSWORD"},
+		{name: "env template", run: "deploy --password ${{ env('BEAR_TEST_DB_PASSWORD') }}", wantErr: "invalid vars"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{
+				"bear.config.yml": `name: test
+templates: true
+secrets:
+  DB_PASSWORD:
+    env: BEAR_TEST_DB_PASSWORD
+targets:
+  ok:
+    steps:
+      - name: Deploy
+        run: "` + tt.run + `"
+`,
+				"api/bear.artifact.yml": "name: api\ntarget: ok\n",
+			}
+			for name, content := range files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("failed to create dir: %v", err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+
+			err := PlanWithOptions(context.Background(), filepath.Join(dir, "bear.config.yml"), Options{StepExecutor: &FakeExecutor{}})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("plan failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error containing '%s', got %v", tt.wantErr, err)
+			}
+
+			data, _ := os.ReadFile(config.PlanFilePath(dir))
+			if strings.Contains(string(data), "s3cret-password") {
+				t.Errorf("expected no secret value in the plan file, got:\n%s", data)
+			}
+			if tt.wantErr == "" && !strings.Contains(string(data), "$DB_PASSWORD") {
+				t.Errorf("expected the plan to keep $DB_PASSWORD for apply, got:\n%s", data)
+			}
+		})
+	}
+}
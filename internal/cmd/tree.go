@@ -11,14 +11,23 @@ import (
 	"github.com/irevolve/bear/internal/config"
 )
 
-func Tree(configPath string, filterArtifacts []string) error {
+func Tree(configPath string, opts Options) error {
 	p := NewPrinter()
 
-	cfg, err := internal.Load(configPath)
+	sel := opts.selector()
+	if err := sel.Validate(); err != nil {
+		return err
+	}
+
+	cfg, err := internal.Load(configPath, opts.ConfigOverlays)
 	if err != nil {
 		return fmt.Errorf("error loading config: %w", err)
 	}
 
+	if err := checkEnvironment(cfg, opts.Environment); err != nil {
+		return err
+	}
+
 	rootPath := filepath.Dir(configPath)
 	if rootPath == "." {
 		rootPath, _ = os.Getwd()
@@ -30,7 +39,7 @@ func Tree(configPath string, filterArtifacts []string) error {
 	}
 
 	// Load lock file for status info
-	lockPath := filepath.Join(rootPath, "bear.lock.yml")
+	lockPath := config.LockFilePath(rootPath, opts.Environment)
 	lockFile, _ := config.LoadLock(lockPath)
 
 	// Build artifact map
@@ -47,12 +56,17 @@ func Tree(configPath string, filterArtifacts []string) error {
 		}
 	}
 
-	p.BearHeader(fmt.Sprintf("Dependency Tree: %s", cfg.Name))
+	title := fmt.Sprintf("Dependency Tree: %s", cfg.Name)
+	if opts.Environment != "" {
+		title += fmt.Sprintf(" (%s)", opts.Environment)
+	}
+	p.BearHeader(title)
 
-	// Filter or show all
-	if len(filterArtifacts) > 0 {
+	// Show specific artifacts, the artifacts matching tags or all
+	shown := artifacts
+	if len(opts.Artifacts) > 0 && len(opts.Tags) == 0 && len(opts.ExcludeTags) == 0 {
 		// Show specific artifacts
-		for i, name := range filterArtifacts {
+		for i, name := range opts.Artifacts {
 			if a, ok := artifactMap[name]; ok {
 				if i > 0 {
 					p.Blank()
@@ -63,13 +77,18 @@ func Tree(configPath string, filterArtifacts []string) error {
 			}
 		}
 	} else {
+		shown = internal.FilterArtifacts(artifacts, sel)
+		if len(shown) == 0 && !sel.IsEmpty() {
+			p.Printf("  No artifacts found matching: %s\n", sel)
+		}
+
 		// Full tree: Show from libraries to services
-		printFullDependencyTree(p, artifacts, artifactMap, dependents, lockFile)
+		printFullDependencyTree(p, shown, artifactMap, dependents, lockFile)
 	}
 
 	// Statistics
 	libs := 0
-	for _, a := range artifacts {
+	for _, a := range shown {
 		if a.Artifact.IsLib {
 			libs++
 		}
@@ -77,7 +96,7 @@ func Tree(configPath string, filterArtifacts []string) error {
 	p.Blank()
 	p.Println(p.dim(strings.Repeat("─", 40)))
 	p.Printf("  Total: %d artifacts (%d services, %d libraries)\n",
-		len(artifacts), len(artifacts)-libs, libs)
+		len(shown), len(shown)-libs, libs)
 
 	return nil
 }
@@ -118,8 +137,8 @@ func printFullDependencyTree(p *Printer, artifacts []internal.DiscoveredArtifact
 		for _, a := range services {
 			status := getStatus(p, a, lockFile)
 			target := ""
-			if a.Artifact.Target != "" {
-				target = p.dim(fmt.Sprintf(" → %s", a.Artifact.Target))
+			if targets := a.Artifact.TargetNames(); len(targets) > 0 {
+				target = p.dim(fmt.Sprintf(" → %s", strings.Join(targets, ", ")))
 			}
 			p.Printf("   %s%s%s\n", p.bold(a.Artifact.Name), target, status)
 
@@ -147,11 +166,32 @@ func getStatus(p *Printer, a internal.DiscoveredArtifact, lockFile *config.LockF
 	if lockFile == nil {
 		return ""
 	}
-	if lockFile.IsPinned(a.Artifact.Name) {
+	if len(a.Artifact.Targets) == 0 {
+		return entryStatus(p, lockFile, a.Artifact.Name, "")
+	}
+
+	// Artifacts with several targets show the status of each target
+	status := ""
+	for _, target := range a.Artifact.TargetNames() {
+		status += entryStatus(p, lockFile, a.Artifact.LockKey(target), target+": ")
+	}
+	return status
+}
+
+// entryStatus formats the lock entry stored under key, prefixed with label
+func entryStatus(p *Printer, lockFile *config.LockFile, key, label string) string {
+	if lockFile.IsPinned(key) {
+		if label != "" {
+			return p.yellow(fmt.Sprintf(" [%s📌]", label))
+		}
 		return p.yellow(" 📌")
 	}
-	if entry, ok := lockFile.Artifacts[a.Artifact.Name]; ok {
-		return p.dim(fmt.Sprintf(" [%s]", entry.Version))
+	if entry, ok := lockFile.Artifacts[key]; ok {
+		status := fmt.Sprintf(" [%s%s]", label, entry.Version)
+		for _, k := range sortedKeys(entry.Outputs) {
+			status += fmt.Sprintf(" %s=%s", k, entry.Outputs[k])
+		}
+		return p.dim(status)
 	}
 	return ""
 }
@@ -161,8 +201,8 @@ func printArtifactTree(p *Printer, a internal.DiscoveredArtifact, artifactMap ma
 	status := getStatus(p, a, lockFile)
 	extra := ""
 
-	if !a.Artifact.IsLib && a.Artifact.Target != "" {
-		extra = p.dim(fmt.Sprintf(" → %s", a.Artifact.Target))
+	if targets := a.Artifact.TargetNames(); !a.Artifact.IsLib && len(targets) > 0 {
+		extra = p.dim(fmt.Sprintf(" → %s", strings.Join(targets, ", ")))
 	}
 
 	if isRoot {
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/irevolve/bear/internal"
+	"github.com/irevolve/bear/internal/config"
+)
+
+// varDecl is a var declaration with the language or target declaring it
+type varDecl struct {
+	config.VarDecl
+	owner string // e.g. "target 'cloudrun'"
+}
+
+// declaredVars returns the var declarations of a language and a target.
+// Declarations of the target override those of the language.
+func declaredVars(cfg *config.Config, targetName, langName string) map[string]varDecl {
+	decls := make(map[string]varDecl)
+	if lang, ok := cfg.Languages[langName]; ok {
+		for name, d := range lang.Declare {
+			decls[name] = varDecl{VarDecl: d, owner: fmt.Sprintf("language '%s'", langName)}
+		}
+	}
+	if t, ok := cfg.Targets[targetName]; ok {
+		for name, d := range t.Declare {
+			decls[name] = varDecl{VarDecl: d, owner: fmt.Sprintf("target '%s'", targetName)}
+		}
+	}
+	return decls
+}
+
+// verifyVars checks the merged vars of an artifact against the declarations
+// of a language and target. Secrets count as set, their values are only
+// known at apply time. Values with templates are checked after bear plan
+// evaluated them.
+//
+// $VAR references are resolved as the steps will see them, from the vars
+// and the environment. Values that reference a secret are not checked.
+func verifyVars(cfg *config.Config, targetName, langName, env string, vars map[string]string) []string {
+	decls := declaredVars(cfg, targetName, langName)
+	secrets := secretDefinitions(cfg, env)
+	resolved := resolveVars(vars)
+
+	var problems []string
+	for _, name := range sortedKeys(decls) {
+		if _, ok := secrets[name]; ok {
+			continue
+		}
+		if cfg.Templates && strings.Contains(vars[name], templateOpen) {
+			continue
+		}
+		if referencesSecret(vars[name], secrets) {
+			continue
+		}
+		d := decls[name]
+		if err := d.Check(resolved[name]); err != nil {
+			problems = append(problems, fmt.Sprintf("%s var %s: %v", d.owner, name, err))
+		}
+	}
+	return problems
+}
+
+// referencesSecret reports whether a value references a secret
+func referencesSecret(value string, secrets map[string]config.Secret) bool {
+	found := false
+	os.Expand(value, func(name string) string {
+		if _, ok := secrets[name]; ok {
+			found = true
+		}
+		return ""
+	})
+	return found
+}
+
+// artifactInputs are the vars and steps of an action, with templates evaluated
+type artifactInputs struct {
+	vars  map[string]string
+	steps []config.Step
+}
+
+// prepareInputs merges the vars of an action for its target and, if templates are enabled,
+// evaluates the templates in its vars and steps for the given commit
+func prepareInputs(cfg *config.Config, env string, action internal.PlannedAction, tmpl *templateSource, commit string) (artifactInputs, error) {
+	a := action.Artifact
+	in := artifactInputs{
+		vars:  mergeVars(cfg, action.Target, a.Language, env, a.Artifact.TargetVars(action.Target)),
+		steps: action.Steps,
+	}
+	if !cfg.Templates {
+		return in, nil
+	}
+
+	vars, steps, err := renderVarsAndSteps(in.vars, in.steps, tmpl.values(a, action.Target, commit), secretEnvNames(cfg))
+	if err != nil {
+		return in, err
+	}
+	return artifactInputs{vars: vars, steps: steps}, nil
+}
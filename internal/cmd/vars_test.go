@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/irevolve/bear/internal"
+	"github.com/irevolve/bear/internal/config"
+)
+
+func TestVerifyVars(t *testing.T) {
+	cfg := &config.Config{
+		Languages: map[string]config.Language{
+			"go": {Declare: map[string]config.VarDecl{
+				"COVERAGE": {Type: config.VarInt, Default: "80"},
+				"PROJECT":  {},
+			}},
+		},
+		Targets: map[string]config.Target{
+			"cloudrun": {Declare: map[string]config.VarDecl{
+				"PROJECT":     {Required: true, Pattern: "[a-z][a-z0-9-]+"},
+				"MEMORY":      {Pattern: "[0-9]+(Mi|Gi)", Default: "512Mi"},
+				"DB_PASSWORD": {Required: true},
+			}},
+		},
+		Secrets: map[string]config.Secret{"DB_PASSWORD": {Env: "DB_PASSWORD"}},
+		Environments: map[string]config.Environment{
+			"prod": {Vars: map[string]string{"PROJECT": "acme-prod"}},
+		},
+	}
+	artifact := func(vars map[string]string) internal.DiscoveredArtifact {
+		return internal.DiscoveredArtifact{
+			Artifact: &config.Artifact{Name: "api", Target: "cloudrun", Vars: vars},
+			Language: "go",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		vars   map[string]string
+		env    string
+		setenv map[string]string
+		want   []string
+	}{
+		{
+			name: "missing required var",
+			want: []string{"target 'cloudrun' var PROJECT: required but not set"},
+		},
+		{
+			name: "set by environment",
+			env:  "prod",
+		},
+		{
+			name: "invalid values",
+			vars: map[string]string{"PROJECT": "acme", "COVERAGE": "high", "MEMORY": "1G"},
+			want: []string{
+				"language 'go' var COVERAGE: 'high' is not an integer",
+				"target 'cloudrun' var MEMORY: '1G' does not match [0-9]+(Mi|Gi)",
+			},
+		},
+		{
+			name:   "reference resolved from environment",
+			vars:   map[string]string{"PROJECT": "${BEAR_TEST_GCP_PROJECT}"},
+			setenv: map[string]string{"BEAR_TEST_GCP_PROJECT": "acme-dev"},
+		},
+		{
+			name: "reference to unset variable",
+			vars: map[string]string{"PROJECT": "$BEAR_TEST_UNSET_PROJECT"},
+			want: []string{"target 'cloudrun' var PROJECT: required but not set"},
+		},
+		{
+			name:   "reference to secret",
+			vars:   map[string]string{"PROJECT": "acme-$DB_PASSWORD"},
+			setenv: map[string]string{"DB_PASSWORD": "Not Valid!"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.setenv {
+				t.Setenv(k, v)
+			}
+			a := artifact(tt.vars)
+			vars := mergeVars(cfg, a.Artifact.Target, a.Language, tt.env, a.Artifact.Vars)
+			got := verifyVars(cfg, a.Artifact.Target, a.Language, tt.env, vars)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMergeVars_DeclaredDefaults(t *testing.T) {
+	cfg := &config.Config{
+		Vars: map[string]string{"REGION": "eu"},
+		Targets: map[string]config.Target{
+			"cloudrun": {Declare: map[string]config.VarDecl{
+				"REGION": {Default: "us"},
+				"MEMORY": {Default: "512Mi"},
+			}},
+		},
+	}
+
+	vars := mergeVars(cfg, "cloudrun", "", "", nil)
+	if vars["REGION"] != "eu" {
+		t.Errorf("expected project var to win over the default, got '%s'", vars["REGION"])
+	}
+	if vars["MEMORY"] != "512Mi" {
+		t.Errorf("expected default for unset var, got '%s'", vars["MEMORY"])
+	}
+}
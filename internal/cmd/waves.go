@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+// deployWaves groups plan artifacts into waves of indices. Every artifact
+// only depends on artifacts from earlier waves. Dependencies that are not
+// part of the plan are treated as already deployed.
+func deployWaves(artifacts []config.PlanArtifact) ([][]int, error) {
+	// Map names to plan indices
+	indices := make(map[string][]int)
+	for i, a := range artifacts {
+		indices[a.Name] = append(indices[a.Name], i)
+	}
+
+	// Count unresolved dependencies and build reverse edges
+	pending := make([]int, len(artifacts))
+	dependents := make([][]int, len(artifacts))
+	for i, a := range artifacts {
+		for _, dep := range a.Depends {
+			for _, j := range indices[dep] {
+				if j == i {
+					continue
+				}
+				pending[i]++
+				dependents[j] = append(dependents[j], i)
+			}
+		}
+	}
+
+	var wave []int
+	for i := range artifacts {
+		if pending[i] == 0 {
+			wave = append(wave, i)
+		}
+	}
+
+	var waves [][]int
+	done := 0
+	for len(wave) > 0 {
+		waves = append(waves, wave)
+		done += len(wave)
+
+		var next []int
+		for _, i := range wave {
+			for _, j := range dependents[i] {
+				pending[j]--
+				if pending[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+		sort.Ints(next)
+		wave = next
+	}
+
+	if done < len(artifacts) {
+		var cyclic []string
+		for i, a := range artifacts {
+			if pending[i] > 0 {
+				cyclic = append(cyclic, a.Name)
+			}
+		}
+		return nil, fmt.Errorf("circular dependency between: %s", strings.Join(cyclic, ", "))
+	}
+
+	return waves, nil
+}
+
+// failedDependency returns the first dependency of the artifact that did
+// not deploy, or "" if all dependencies are fine.
+func failedDependency(artifact config.PlanArtifact, failed map[string]bool) string {
+	for _, dep := range artifact.Depends {
+		if failed[dep] {
+			return dep
+		}
+	}
+	return ""
+}
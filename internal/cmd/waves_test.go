@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+func TestDeployWaves(t *testing.T) {
+	tests := []struct {
+		name      string
+		artifacts []config.PlanArtifact
+		expected  [][]int
+	}{
+		{
+			name: "independent artifacts share one wave",
+			artifacts: []config.PlanArtifact{
+				{Name: "user-api"},
+				{Name: "order-api"},
+			},
+			expected: [][]int{{0, 1}},
+		},
+		{
+			name: "dependency runs first",
+			artifacts: []config.PlanArtifact{
+				{Name: "order-api", Depends: []string{"user-api"}},
+				{Name: "user-api"},
+			},
+			expected: [][]int{{1}, {0}},
+		},
+		{
+			name: "diamond",
+			artifacts: []config.PlanArtifact{
+				{Name: "gateway", Depends: []string{"user-api", "order-api"}},
+				{Name: "user-api", Depends: []string{"db"}},
+				{Name: "order-api", Depends: []string{"db"}},
+				{Name: "db"},
+			},
+			expected: [][]int{{3}, {1, 2}, {0}},
+		},
+		{
+			name: "dependencies outside the plan are ignored",
+			artifacts: []config.PlanArtifact{
+				{Name: "user-api", Depends: []string{"shared-go"}},
+			},
+			expected: [][]int{{0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waves, err := deployWaves(tt.artifacts)
+			if err != nil {
+				t.Fatalf("deployWaves failed: %v", err)
+			}
+			if !reflect.DeepEqual(waves, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, waves)
+			}
+		})
+	}
+}
+
+func TestDeployWaves_Cycle(t *testing.T) {
+	artifacts := []config.PlanArtifact{
+		{Name: "a", Depends: []string{"b"}},
+		{Name: "b", Depends: []string{"a"}},
+		{Name: "c"},
+	}
+
+	if _, err := deployWaves(artifacts); err == nil {
+		t.Error("expected error for circular dependency")
+	}
+}
+
+func TestFailedDependency(t *testing.T) {
+	artifact := config.PlanArtifact{Name: "api", Depends: []string{"db", "cache"}}
+
+	if dep := failedDependency(artifact, map[string]bool{}); dep != "" {
+		t.Errorf("expected no failed dependency, got '%s'", dep)
+	}
+	if dep := failedDependency(artifact, map[string]bool{"cache": true}); dep != "cache" {
+		t.Errorf("expected 'cache', got '%s'", dep)
+	}
+}
@@ -2,17 +2,68 @@ package config
 
 import (
 	"os"
-
-	"gopkg.in/yaml.v3"
 )
 
 // Artifact defines a single deployable artifact (bear.artifact.yml)
 type Artifact struct {
-	Name    string            `yaml:"name"`
-	Target  string            `yaml:"target"`            // Reference to Target
-	Vars    map[string]string `yaml:"vars,omitempty"`    // Variables for the target
-	Depends []string          `yaml:"depends,omitempty"` // Dependencies to other artifacts
-	IsLib   bool              `yaml:"-"`                 // Set by scanner for libraries
+	Name     string            `yaml:"name"`
+	Language string            `yaml:"language,omitempty"` // Reference to Language, skips detection
+	Target   string            `yaml:"target,omitempty"`   // Reference to Target
+	Targets  []ArtifactTarget  `yaml:"targets,omitempty"`  // Several targets, instead of target
+	Vars     map[string]string `yaml:"vars,omitempty"`     // Variables for the target
+	Depends  []string          `yaml:"depends,omitempty"`  // Dependencies to other artifacts
+	Tags     []string          `yaml:"tags,omitempty"`     // Labels to select artifacts by, e.g. with bear plan --tag
+	Watch    []string          `yaml:"watch,omitempty"`    // Extra paths or globs that trigger a rebuild (relative to the artifact directory)
+	Ignore   []string          `yaml:"ignore,omitempty"`   // Globs of files that never trigger a rebuild (relative to the artifact directory)
+	IsLib    bool              `yaml:"-"`                  // Set by scanner for libraries
+}
+
+// ArtifactTarget is one of several targets of an artifact
+type ArtifactTarget struct {
+	Name string            `yaml:"name"`           // Reference to Target
+	Vars map[string]string `yaml:"vars,omitempty"` // Override the artifact vars for this target
+}
+
+// TargetNames returns the targets the artifact deploys to
+func (a *Artifact) TargetNames() []string {
+	if len(a.Targets) == 0 {
+		if a.Target == "" {
+			return nil
+		}
+		return []string{a.Target}
+	}
+	names := make([]string, len(a.Targets))
+	for i, t := range a.Targets {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// TargetVars returns the artifact vars with the overrides of a target
+func (a *Artifact) TargetVars(target string) map[string]string {
+	vars := make(map[string]string, len(a.Vars))
+	for k, v := range a.Vars {
+		vars[k] = v
+	}
+	for _, t := range a.Targets {
+		if t.Name != target {
+			continue
+		}
+		for k, v := range t.Vars {
+			vars[k] = v
+		}
+	}
+	return vars
+}
+
+// LockKey returns the key of the lock entry for a deployment to target.
+// Artifacts with a single target use their name, artifacts with a targets
+// list track each target as "name@target".
+func (a *Artifact) LockKey(target string) string {
+	if len(a.Targets) == 0 {
+		return a.Name
+	}
+	return a.Name + "@" + target
 }
 
 // LoadArtifact loads a bear.artifact.yml file
@@ -23,7 +74,7 @@ func LoadArtifact(path string) (*Artifact, error) {
 	}
 
 	var artifact Artifact
-	if err := yaml.Unmarshal(data, &artifact); err != nil {
+	if err := decodeStrict(path, data, &artifact); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Hash returns the SHA-256 of the YAML encoding of v.
+// Map keys are sorted by the encoder, so the result is deterministic.
+func Hash(v any) (string, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return HashBytes(data), nil
+}
+
+// HashBytes returns the hex encoded SHA-256 of data
+func HashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashFile returns the SHA-256 of a file's contents, or "" if it does not exist
+func HashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return HashBytes(data), nil
+}
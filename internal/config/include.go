@@ -0,0 +1,285 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Definition kinds used as prefix of the keys in Config.Sources
+const (
+	SourceLanguage    = "language"
+	SourceTarget      = "target"
+	SourceVar         = "var"
+	SourceEnvironment = "environment"
+	SourceSecret      = "secret"
+)
+
+// SourcePreset is the source of definitions resolved from presets
+const SourcePreset = "preset"
+
+// includeKeys are the top-level keys allowed in included files
+var includeKeys = map[string]bool{"languages": true, "targets": true, "vars": true}
+
+// Source returns the file a definition came from, e.g. Source("target", "docker").
+// Paths are relative to the directory of the main config file.
+func (c *Config) Source(kind, name string) string {
+	return c.Sources[kind+"/"+name]
+}
+
+// SetSource records the file a definition came from
+func (c *Config) SetSource(kind, name, source string) {
+	if c.Sources == nil {
+		c.Sources = make(map[string]string)
+	}
+	c.Sources[kind+"/"+name] = source
+}
+
+// recordSources records source as the origin of all definitions in from
+func (c *Config) recordSources(from *Config, source string) {
+	for name := range from.Languages {
+		c.SetSource(SourceLanguage, name, source)
+	}
+	for name := range from.Targets {
+		c.SetSource(SourceTarget, name, source)
+	}
+	for name := range from.Vars {
+		c.SetSource(SourceVar, name, source)
+	}
+	for name := range from.Environments {
+		c.SetSource(SourceEnvironment, name, source)
+	}
+	for name := range from.Secrets {
+		c.SetSource(SourceSecret, name, source)
+	}
+}
+
+// resolveIncludes merges the languages, targets and vars of the included
+// files. Definitions of the main file take precedence over included ones,
+// the same definition in two included files is an error.
+func (c *Config) resolveIncludes(base, main string) error {
+	files, err := includeFiles(base, c.Include)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range files {
+		source := sourceName(base, path)
+		inc, err := loadInclude(path)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range sortedNames(inc.Languages) {
+			if add, err := c.mergeDefinition(SourceLanguage, name, main, source); err != nil {
+				return err
+			} else if add {
+				c.Languages = overlayMap(c.Languages, map[string]Language{name: inc.Languages[name]})
+			}
+		}
+		for _, name := range sortedNames(inc.Targets) {
+			if add, err := c.mergeDefinition(SourceTarget, name, main, source); err != nil {
+				return err
+			} else if add {
+				c.Targets = overlayMap(c.Targets, map[string]Target{name: inc.Targets[name]})
+			}
+		}
+		for _, name := range sortedNames(inc.Vars) {
+			if add, err := c.mergeDefinition(SourceVar, name, main, source); err != nil {
+				return err
+			} else if add {
+				c.Vars = overlayMap(c.Vars, map[string]string{name: inc.Vars[name]})
+			}
+		}
+	}
+
+	return nil
+}
+
+// mergeDefinition reports whether a definition of an included file is added.
+// Definitions of the main file win, a definition in two included files is
+// a conflict.
+func (c *Config) mergeDefinition(kind, name, main, source string) (bool, error) {
+	switch existing := c.Source(kind, name); existing {
+	case "":
+		c.SetSource(kind, name, source)
+		return true, nil
+	case main:
+		return false, nil
+	default:
+		return false, fmt.Errorf("%s '%s' is defined in both %s and %s", kind, name, existing, source)
+	}
+}
+
+// includeFiles expands the include patterns relative to base. Globs may
+// match no files, plain paths must exist. Every file is returned once.
+func includeFiles(base string, patterns []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		path := pattern
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(base, path)
+		}
+
+		matches := []string{path}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			matches, err = filepath.Glob(path)
+			if err != nil {
+				return nil, fmt.Errorf("invalid include pattern '%s': %w", pattern, err)
+			}
+			sort.Strings(matches)
+		} else if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("include '%s' not found", pattern)
+		}
+
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				files = append(files, match)
+			}
+		}
+	}
+	return files, nil
+}
+
+// loadInclude reads an included file, which may only define languages,
+// targets and vars
+func loadInclude(path string) (*Config, error) {
+	cfg, err := loadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// The file is valid YAML, loadFile would have failed otherwise
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) > 0 && root.Content[0].Kind == yaml.MappingNode {
+		doc := root.Content[0]
+		for i := 0; i < len(doc.Content); i += 2 {
+			if key := doc.Content[i]; !includeKeys[key.Value] {
+				return nil, &PositionError{Path: path, Line: key.Line, Column: key.Column,
+					Msg: fmt.Sprintf("'%s' is not allowed in included files (only languages, targets and vars)", key.Value)}
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// ApplyOverlay applies an overlay file on top of the config. Languages,
+// targets, environments, secrets and vars are replaced per name, settings
+// like executor or state_lock and hook lists are replaced if set.
+func (c *Config) ApplyOverlay(path string) error {
+	ov, err := loadFile(path)
+	if err != nil {
+		return fmt.Errorf("overlay %s: %w", path, err)
+	}
+	if len(ov.Include) > 0 {
+		return fmt.Errorf("overlay %s: include is not allowed in overlays", path)
+	}
+
+	if ov.Name != "" {
+		c.Name = ov.Name
+	}
+	for _, name := range ov.Use.Languages {
+		if !contains(c.Use.Languages, name) {
+			c.Use.Languages = append(c.Use.Languages, name)
+		}
+	}
+	for _, name := range ov.Use.Targets {
+		if !contains(c.Use.Targets, name) {
+			c.Use.Targets = append(c.Use.Targets, name)
+		}
+	}
+
+	c.Languages = overlayMap(c.Languages, ov.Languages)
+	c.Targets = overlayMap(c.Targets, ov.Targets)
+	c.Vars = overlayMap(c.Vars, ov.Vars)
+	c.Environments = overlayMap(c.Environments, ov.Environments)
+	c.Secrets = overlayMap(c.Secrets, ov.Secrets)
+	c.recordSources(ov, path)
+
+	if ov.StateLock != (StateLock{}) {
+		c.StateLock = ov.StateLock
+	}
+	if ov.Cache != (Cache{}) {
+		c.Cache = ov.Cache
+	}
+	if ov.ChangeDetection != "" {
+		c.ChangeDetection = ov.ChangeDetection
+	}
+	if ov.Executor != "" {
+		c.Executor = ov.Executor
+	}
+	if ov.Templates {
+		c.Templates = true
+	}
+	overlaySteps(&c.Hooks.PrePlan, ov.Hooks.PrePlan)
+	overlaySteps(&c.Hooks.PostPlan, ov.Hooks.PostPlan)
+	overlaySteps(&c.Hooks.PreApply, ov.Hooks.PreApply)
+	overlaySteps(&c.Hooks.PostApply, ov.Hooks.PostApply)
+	overlaySteps(&c.Hooks.OnFailure, ov.Hooks.OnFailure)
+
+	return nil
+}
+
+// overlayMap sets all entries of overlay in base
+func overlayMap[V any](base, overlay map[string]V) map[string]V {
+	if len(overlay) == 0 {
+		return base
+	}
+	if base == nil {
+		base = make(map[string]V, len(overlay))
+	}
+	for k, v := range overlay {
+		base[k] = v
+	}
+	return base
+}
+
+// overlaySteps replaces the steps if the overlay defines any
+func overlaySteps(steps *[]Step, overlay []Step) {
+	if len(overlay) > 0 {
+		*steps = overlay
+	}
+}
+
+// sourceName returns path relative to base, or path itself if that fails
+func sourceName(base, path string) string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// sortedNames returns the keys of m in sorted order
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
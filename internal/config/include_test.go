@@ -0,0 +1,216 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files relative to dir, creating directories as needed
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestLoad_Include(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"bear.config.yml": `name: test
+include:
+  - ci/targets.yml
+  - ci/languages/*.yml
+  - ci/none/*.yml
+targets:
+  docker:
+    steps:
+      - name: Main
+        run: main
+vars:
+  REGION: eu
+`,
+		"ci/targets.yml": `targets:
+  docker:
+    steps:
+      - name: Included
+        run: included
+  cloudrun:
+    steps:
+      - name: Deploy
+        run: deploy
+vars:
+  REGION: us
+  REGISTRY: ghcr.io
+`,
+		"ci/languages/go.yml": `languages:
+  go:
+    detection:
+      files: [go.mod]
+`,
+	})
+
+	cfg, err := Load(filepath.Join(dir, "bear.config.yml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if got := cfg.Targets["docker"].Steps[0].Name; got != "Main" {
+		t.Errorf("expected main config to override include, got step '%s'", got)
+	}
+	if cfg.Targets["cloudrun"].Name != "cloudrun" {
+		t.Errorf("expected included target 'cloudrun' with name, got %+v", cfg.Targets["cloudrun"])
+	}
+	if cfg.Languages["go"].Name != "go" {
+		t.Errorf("expected included language 'go' from glob, got %+v", cfg.Languages)
+	}
+	if cfg.Vars["REGION"] != "eu" || cfg.Vars["REGISTRY"] != "ghcr.io" {
+		t.Errorf("unexpected vars: %v", cfg.Vars)
+	}
+
+	tests := []struct {
+		kind, name, want string
+	}{
+		{SourceTarget, "docker", "bear.config.yml"},
+		{SourceTarget, "cloudrun", "ci/targets.yml"},
+		{SourceLanguage, "go", "ci/languages/go.yml"},
+		{SourceVar, "REGION", "bear.config.yml"},
+		{SourceVar, "REGISTRY", "ci/targets.yml"},
+	}
+	for _, tt := range tests {
+		if got := cfg.Source(tt.kind, tt.name); got != tt.want {
+			t.Errorf("Source(%s, %s) = '%s', want '%s'", tt.kind, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "conflict",
+			files: map[string]string{
+				"bear.config.yml": "name: test\ninclude: [a.yml, b.yml]\n",
+				"a.yml":           "targets:\n  docker:\n    steps: []\n",
+				"b.yml":           "targets:\n  docker:\n    steps: []\n",
+			},
+			want: "target 'docker' is defined in both a.yml and b.yml",
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"bear.config.yml": "name: test\ninclude: [missing.yml]\n",
+			},
+			want: "include 'missing.yml' not found",
+		},
+		{
+			name: "disallowed key",
+			files: map[string]string{
+				"bear.config.yml": "name: test\ninclude: [a.yml]\n",
+				"a.yml":           "name: other\n",
+			},
+			want: "'name' is not allowed in included files",
+		},
+		{
+			name: "nested include",
+			files: map[string]string{
+				"bear.config.yml": "name: test\ninclude: [a.yml]\n",
+				"a.yml":           "include: [b.yml]\n",
+			},
+			want: "'include' is not allowed in included files",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			_, err := Load(filepath.Join(dir, "bear.config.yml"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing '%s', got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestApplyOverlay(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"bear.config.yml": `name: test
+executor: shell
+state_lock:
+  backend: local
+targets:
+  docker:
+    steps:
+      - name: Deploy
+        run: deploy
+  cloudrun:
+    steps:
+      - name: Deploy
+        run: deploy
+vars:
+  REGION: eu
+hooks:
+  pre_plan:
+    - name: Notify
+      run: notify
+`,
+		"ci.yml": `executor: dry-run
+state_lock:
+  backend: none
+targets:
+  docker:
+    steps:
+      - name: CI
+        run: ci
+vars:
+  REGISTRY: ci.example.com
+`,
+	})
+
+	cfg, err := Load(filepath.Join(dir, "bear.config.yml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	overlay := filepath.Join(dir, "ci.yml")
+	if err := cfg.ApplyOverlay(overlay); err != nil {
+		t.Fatalf("ApplyOverlay failed: %v", err)
+	}
+
+	if cfg.Executor != "dry-run" || cfg.StateLock.Backend != "none" {
+		t.Errorf("expected settings to be overridden, got executor '%s', state lock '%s'", cfg.Executor, cfg.StateLock.Backend)
+	}
+	if got := cfg.Targets["docker"].Steps[0].Name; got != "CI" {
+		t.Errorf("expected overlay target, got step '%s'", got)
+	}
+	if cfg.Targets["docker"].Name != "docker" {
+		t.Error("expected overlay target to have its name populated")
+	}
+	if _, ok := cfg.Targets["cloudrun"]; !ok {
+		t.Error("expected target 'cloudrun' to be kept")
+	}
+	if cfg.Vars["REGION"] != "eu" || cfg.Vars["REGISTRY"] != "ci.example.com" {
+		t.Errorf("unexpected vars: %v", cfg.Vars)
+	}
+	if len(cfg.Hooks.PrePlan) != 1 {
+		t.Errorf("expected hooks to be kept, got %v", cfg.Hooks.PrePlan)
+	}
+	if got := cfg.Source(SourceTarget, "docker"); got != overlay {
+		t.Errorf("expected source '%s', got '%s'", overlay, got)
+	}
+	if got := cfg.Source(SourceTarget, "cloudrun"); got != "bear.config.yml" {
+		t.Errorf("expected source 'bear.config.yml', got '%s'", got)
+	}
+}
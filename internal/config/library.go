@@ -2,14 +2,16 @@ package config
 
 import (
 	"os"
-
-	"gopkg.in/yaml.v3"
 )
 
 // Library defines a shared library (bear.lib.yml)
 type Library struct {
-	Name    string   `yaml:"name"`
-	Depends []string `yaml:"depends,omitempty"` // Dependencies to other artifacts/libraries
+	Name     string   `yaml:"name"`
+	Language string   `yaml:"language,omitempty"` // Reference to Language, skips detection
+	Depends  []string `yaml:"depends,omitempty"`  // Dependencies to other artifacts/libraries
+	Tags     []string `yaml:"tags,omitempty"`     // Labels to select libraries by
+	Watch    []string `yaml:"watch,omitempty"`    // Extra paths or globs that trigger a rebuild
+	Ignore   []string `yaml:"ignore,omitempty"`   // Globs of files that never trigger a rebuild
 }
 
 // LoadLibrary loads a bear.lib.yml file
@@ -20,7 +22,7 @@ func LoadLibrary(path string) (*Library, error) {
 	}
 
 	var lib Library
-	if err := yaml.Unmarshal(data, &lib); err != nil {
+	if err := decodeStrict(path, data, &lib); err != nil {
 		return nil, err
 	}
 
@@ -30,8 +32,12 @@ func LoadLibrary(path string) (*Library, error) {
 // ToArtifact converts a Library to an Artifact for unified handling
 func (l *Library) ToArtifact() *Artifact {
 	return &Artifact{
-		Name:    l.Name,
-		Depends: l.Depends,
-		IsLib:   true,
+		Name:     l.Name,
+		Language: l.Language,
+		Depends:  l.Depends,
+		Tags:     l.Tags,
+		Watch:    l.Watch,
+		Ignore:   l.Ignore,
+		IsLib:    true,
 	}
 }
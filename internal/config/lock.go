@@ -1,7 +1,9 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -9,16 +11,49 @@ import (
 
 // LockEntry contains the deployment status of an artifact
 type LockEntry struct {
-	Commit    string `yaml:"commit"`            // Last successfully deployed commit
-	Timestamp string `yaml:"timestamp"`         // Time of deployment
-	Version   string `yaml:"version,omitempty"` // Optional version
-	Target    string `yaml:"target"`            // Used target template
-	Pinned    bool   `yaml:"pinned,omitempty"`  // If true, this artifact is not automatically updated
+	Commit    string            `yaml:"commit"`              // Last successfully deployed commit
+	Timestamp string            `yaml:"timestamp"`           // Time of deployment
+	Version   string            `yaml:"version,omitempty"`   // Optional version
+	Target    string            `yaml:"target"`              // Used target template
+	Pinned    bool              `yaml:"pinned,omitempty"`    // If true, this artifact is not automatically updated
+	TreeHash  string            `yaml:"tree_hash,omitempty"` // Content hash of the artifact (tree change detection)
+	Outputs   map[string]string `yaml:"outputs,omitempty"`   // Step outputs selected by the target
+}
+
+// HistoryLimit is the maximum number of history entries kept per artifact
+const HistoryLimit = 10
+
+// Deployment results recorded in the history
+const (
+	ResultSuccess = "success"
+	ResultFailed  = "failed"
+)
+
+// HistoryEntry records a single deployment attempt of an artifact
+type HistoryEntry struct {
+	Commit    string `yaml:"commit"`
+	Version   string `yaml:"version,omitempty"`
+	Target    string `yaml:"target"`
+	Timestamp string `yaml:"timestamp"`
+	Result    string `yaml:"result"` // "success" or "failed"
+
+	RolledBackFrom string `yaml:"rolled_back_from,omitempty"` // Commit replaced by a pin or rollback
 }
 
 // LockFile contains the deployment status of all artifacts
 type LockFile struct {
-	Artifacts map[string]LockEntry `yaml:"artifacts"`
+	Artifacts map[string]LockEntry      `yaml:"artifacts"`
+	History   map[string][]HistoryEntry `yaml:"history,omitempty"` // Oldest first, bounded by HistoryLimit
+}
+
+// LockFilePath returns the path of the lock file for an environment.
+// The default environment ("") uses bear.lock.yml, named environments
+// use bear.lock.<env>.yml.
+func LockFilePath(rootPath, env string) string {
+	if env == "" {
+		return filepath.Join(rootPath, "bear.lock.yml")
+	}
+	return filepath.Join(rootPath, "bear.lock."+env+".yml")
 }
 
 // LoadLock loads the bear.lock.yml file
@@ -62,6 +97,30 @@ func (l *LockFile) GetLastDeployedCommit(artifactName string) string {
 	return ""
 }
 
+// GetTreeHash returns the recorded content hash of an artifact
+func (l *LockFile) GetTreeHash(artifactName string) string {
+	if entry, ok := l.Artifacts[artifactName]; ok {
+		return entry.TreeHash
+	}
+	return ""
+}
+
+// SetTreeHash records the content hash of a deployed artifact
+func (l *LockFile) SetTreeHash(artifactName, hash string) {
+	if entry, ok := l.Artifacts[artifactName]; ok {
+		entry.TreeHash = hash
+		l.Artifacts[artifactName] = entry
+	}
+}
+
+// SetOutputs records the step outputs of a deployed artifact
+func (l *LockFile) SetOutputs(artifactName string, outputs map[string]string) {
+	if entry, ok := l.Artifacts[artifactName]; ok {
+		entry.Outputs = outputs
+		l.Artifacts[artifactName] = entry
+	}
+}
+
 // IsPinned checks if an artifact is pinned
 func (l *LockFile) IsPinned(artifactName string) bool {
 	if entry, ok := l.Artifacts[artifactName]; ok {
@@ -79,10 +138,13 @@ func (l *LockFile) UpdateArtifact(artifactName, commit, target, version string)
 		Target:    target,
 		Pinned:    false,
 	}
+	l.addHistory(artifactName, commit, target, version, ResultSuccess)
 }
 
-// UpdateArtifactPinned updates the deployment status and pins the artifact
+// UpdateArtifactPinned updates the deployment status and pins the artifact.
+// The history records the commit that was deployed before.
 func (l *LockFile) UpdateArtifactPinned(artifactName, commit, target, version string) {
+	previous := l.Artifacts[artifactName].Commit
 	l.Artifacts[artifactName] = LockEntry{
 		Commit:    commit,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
@@ -90,4 +152,83 @@ func (l *LockFile) UpdateArtifactPinned(artifactName, commit, target, version st
 		Target:    target,
 		Pinned:    true,
 	}
+	l.addHistory(artifactName, commit, target, version, ResultSuccess)
+	if previous != "" && previous != commit {
+		history := l.History[artifactName]
+		history[len(history)-1].RolledBackFrom = previous
+	}
+}
+
+// RecordFailure adds a failed deployment to the history.
+// The current entry of the artifact is left untouched.
+func (l *LockFile) RecordFailure(artifactName, commit, target, version string) {
+	l.addHistory(artifactName, commit, target, version, ResultFailed)
+}
+
+// addHistory appends a history entry and drops the oldest entries beyond HistoryLimit
+func (l *LockFile) addHistory(artifactName, commit, target, version, result string) {
+	if l.History == nil {
+		l.History = make(map[string][]HistoryEntry)
+	}
+
+	history := append(l.History[artifactName], HistoryEntry{
+		Commit:    commit,
+		Version:   version,
+		Target:    target,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Result:    result,
+	})
+	if len(history) > HistoryLimit {
+		history = history[len(history)-HistoryLimit:]
+	}
+	l.History[artifactName] = history
+}
+
+// PreviousDeployment returns the successful deployment that lies the given
+// number of steps before the currently deployed commit. Repeated deployments
+// of the same commit count as one step. A rollback to an earlier deployment
+// undoes the deployments in between, so the walk continues before the
+// earlier deployment.
+func (l *LockFile) PreviousDeployment(artifactName string, steps int) (HistoryEntry, error) {
+	if steps < 1 {
+		return HistoryEntry{}, fmt.Errorf("steps must be at least 1")
+	}
+
+	current := l.GetLastDeployedCommit(artifactName)
+	if current == "" {
+		return HistoryEntry{}, fmt.Errorf("artifact '%s' has never been deployed", artifactName)
+	}
+
+	seen := map[string]bool{current: true}
+	found := 0
+	history := l.History[artifactName]
+	for i := len(history) - 1; i >= 0; i-- {
+		entry := history[i]
+		if entry.RolledBackFrom != "" {
+			i = earlierDeployment(history, i)
+		}
+		if entry.Result != ResultSuccess || seen[entry.Commit] {
+			continue
+		}
+		seen[entry.Commit] = true
+		found++
+		if found == steps {
+			return entry, nil
+		}
+	}
+
+	return HistoryEntry{}, fmt.Errorf("no successful deployment %d step(s) back in the history of '%s' (%d available)",
+		steps, artifactName, found)
+}
+
+// earlierDeployment returns the index of the last successful deployment of
+// the commit of history[i] before i, or i if there is none (e.g. a pin to a
+// new commit)
+func earlierDeployment(history []HistoryEntry, i int) int {
+	for j := i - 1; j >= 0; j-- {
+		if history[j].Result == ResultSuccess && history[j].Commit == history[i].Commit {
+			return j
+		}
+	}
+	return i
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -153,6 +154,27 @@ func TestLockFile_UpdateArtifactPinned(t *testing.T) {
 	}
 }
 
+func TestLockFile_TreeHash(t *testing.T) {
+	lock := &LockFile{Artifacts: make(map[string]LockEntry)}
+
+	lock.SetTreeHash("my-service", "f00d")
+	if got := lock.GetTreeHash("my-service"); got != "" {
+		t.Errorf("expected no tree hash for unknown artifact, got '%s'", got)
+	}
+
+	lock.UpdateArtifact("my-service", "abc123", "cloudrun", "v1.0")
+	lock.SetTreeHash("my-service", "f00d")
+	if got := lock.GetTreeHash("my-service"); got != "f00d" {
+		t.Errorf("expected tree hash 'f00d', got '%s'", got)
+	}
+
+	// A new deployment replaces the entry including its tree hash
+	lock.UpdateArtifact("my-service", "def456", "cloudrun", "v1.1")
+	if got := lock.GetTreeHash("my-service"); got != "" {
+		t.Errorf("expected tree hash to be reset, got '%s'", got)
+	}
+}
+
 func TestLockFile_Save(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "bear-test-*")
 	if err != nil {
@@ -181,3 +203,121 @@ func TestLockFile_Save(t *testing.T) {
 		t.Errorf("expected commit 'xyz789', got '%s'", loaded.Artifacts["test"].Commit)
 	}
 }
+
+func TestLockFilePath(t *testing.T) {
+	if got := LockFilePath("/repo", ""); got != filepath.Join("/repo", "bear.lock.yml") {
+		t.Errorf("unexpected default lock path '%s'", got)
+	}
+	if got := LockFilePath("/repo", "staging"); got != filepath.Join("/repo", "bear.lock.staging.yml") {
+		t.Errorf("unexpected staging lock path '%s'", got)
+	}
+}
+
+func TestLockFile_HistoryIsBounded(t *testing.T) {
+	lock := &LockFile{Artifacts: make(map[string]LockEntry)}
+
+	for i := 0; i < HistoryLimit+5; i++ {
+		lock.UpdateArtifact("api", fmt.Sprintf("commit-%d", i), "cloudrun", "v1")
+	}
+	lock.RecordFailure("api", "broken", "cloudrun", "v2")
+
+	history := lock.History["api"]
+	if len(history) != HistoryLimit {
+		t.Fatalf("expected %d history entries, got %d", HistoryLimit, len(history))
+	}
+	last := history[len(history)-1]
+	if last.Commit != "broken" || last.Result != ResultFailed {
+		t.Errorf("expected failed entry 'broken' last, got %+v", last)
+	}
+	if lock.Artifacts["api"].Commit != fmt.Sprintf("commit-%d", HistoryLimit+4) {
+		t.Errorf("failure must not change the current entry, got '%s'", lock.Artifacts["api"].Commit)
+	}
+}
+
+func TestLockFile_PreviousDeployment(t *testing.T) {
+	lock := &LockFile{
+		Artifacts: map[string]LockEntry{"api": {Commit: "ccc"}},
+		History: map[string][]HistoryEntry{
+			"api": {
+				{Commit: "aaa", Result: ResultSuccess},
+				{Commit: "bbb", Result: ResultSuccess},
+				{Commit: "bbb", Result: ResultSuccess},
+				{Commit: "xxx", Result: ResultFailed},
+				{Commit: "ccc", Result: ResultSuccess},
+			},
+		},
+	}
+
+	tests := []struct {
+		steps    int
+		expected string
+		wantErr  bool
+	}{
+		{steps: 1, expected: "bbb"},
+		{steps: 2, expected: "aaa"},
+		{steps: 3, wantErr: true},
+		{steps: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		entry, err := lock.PreviousDeployment("api", tt.steps)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("steps=%d: expected error", tt.steps)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("steps=%d: unexpected error: %v", tt.steps, err)
+		}
+		if entry.Commit != tt.expected {
+			t.Errorf("steps=%d: expected '%s', got '%s'", tt.steps, tt.expected, entry.Commit)
+		}
+	}
+
+	if _, err := lock.PreviousDeployment("unknown", 1); err == nil {
+		t.Error("expected error for never deployed artifact")
+	}
+}
+
+func TestLockFile_PreviousDeployment_AfterRollback(t *testing.T) {
+	lock := &LockFile{Artifacts: make(map[string]LockEntry)}
+	for _, commit := range []string{"aaa", "bbb", "ccc"} {
+		lock.UpdateArtifact("api", commit, "cloudrun", commit)
+	}
+
+	// Roll back from ccc to bbb
+	entry, err := lock.PreviousDeployment("api", 1)
+	if err != nil || entry.Commit != "bbb" {
+		t.Fatalf("expected 'bbb', got '%s' (%v)", entry.Commit, err)
+	}
+	lock.UpdateArtifactPinned("api", "bbb", "cloudrun", "bbb")
+	history := lock.History["api"]
+	if from := history[len(history)-1].RolledBackFrom; from != "ccc" {
+		t.Errorf("expected rollback from 'ccc' in the history, got '%s'", from)
+	}
+
+	// Rolling back again goes further back, not forward to ccc
+	entry, err = lock.PreviousDeployment("api", 1)
+	if err != nil || entry.Commit != "aaa" {
+		t.Fatalf("expected 'aaa', got '%s' (%v)", entry.Commit, err)
+	}
+	lock.UpdateArtifactPinned("api", "aaa", "cloudrun", "aaa")
+	if _, err := lock.PreviousDeployment("api", 1); err == nil {
+		t.Error("expected no deployment before 'aaa'")
+	}
+
+	// A deployment after the rollback is rolled back to the pinned commit
+	lock.UpdateArtifact("api", "ddd", "cloudrun", "ddd")
+	entry, err = lock.PreviousDeployment("api", 1)
+	if err != nil || entry.Commit != "aaa" {
+		t.Errorf("expected 'aaa', got '%s' (%v)", entry.Commit, err)
+	}
+
+	// A pin to a new commit is not a rollback to an earlier deployment
+	lock.UpdateArtifactPinned("api", "eee", "cloudrun", "eee")
+	entry, err = lock.PreviousDeployment("api", 1)
+	if err != nil || entry.Commit != "ddd" {
+		t.Errorf("expected 'ddd', got '%s' (%v)", entry.Commit, err)
+	}
+}
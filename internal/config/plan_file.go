@@ -1,6 +1,8 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"time"
@@ -8,44 +10,109 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// PlanFormatVersion is the schema version of the plan file.
+// It is increased on incompatible changes.
+const PlanFormatVersion = 2
+
+// AffectedFile is a changed file that affects an artifact
+type AffectedFile struct {
+	Path   string `yaml:"path" json:"path"`                         // Relative to the workspace root, slash-separated
+	Reason string `yaml:"reason,omitempty" json:"reason,omitempty"` // Why it affects the artifact, e.g. "watch: ../../proto"
+}
+
+// String formats the file for messages, e.g. "proto/user.proto (watch: ../../proto)"
+func (f AffectedFile) String() string {
+	if f.Reason == "" {
+		return f.Path
+	}
+	return f.Path + " (" + f.Reason + ")"
+}
+
 // PlanArtifact represents a single artifact in the plan file
 type PlanArtifact struct {
-	Name         string            `yaml:"name"`
-	Path         string            `yaml:"path"`
-	Language     string            `yaml:"language"`
-	Target       string            `yaml:"target,omitempty"`
-	Action       string            `yaml:"action"` // "deploy" or "skip"
-	Reason       string            `yaml:"reason"`
-	ChangedFiles []string          `yaml:"changed_files,omitempty"`
-	Vars         map[string]string `yaml:"vars,omitempty"`
-	Steps        []Step            `yaml:"steps,omitempty"` // Deploy steps only (validation already ran)
-	Pinned       bool              `yaml:"pinned,omitempty"`
-	PinCommit    string            `yaml:"pin_commit,omitempty"`
-	IsLib        bool              `yaml:"is_lib,omitempty"`
+	Name           string            `yaml:"name" json:"name"`
+	Path           string            `yaml:"path" json:"path"`
+	Language       string            `yaml:"language" json:"language"`
+	Target         string            `yaml:"target,omitempty" json:"target,omitempty"`
+	Action         string            `yaml:"action" json:"action"` // "deploy" or "skip"
+	Reason         string            `yaml:"reason" json:"reason"`
+	ChangedFiles   []AffectedFile    `yaml:"changed_files,omitempty" json:"changed_files,omitempty"`
+	ChangesUnknown bool              `yaml:"changes_unknown,omitempty" json:"changes_unknown,omitempty"` // changed() matches every pattern
+	Depends        []string          `yaml:"depends,omitempty" json:"depends,omitempty"`                 // Used to order deployments
+	Vars           map[string]string `yaml:"vars,omitempty" json:"vars,omitempty"`
+	Steps          []Step            `yaml:"steps,omitempty" json:"steps,omitempty"` // Deploy steps only (validation already ran)
+	Pinned         bool              `yaml:"pinned,omitempty" json:"pinned,omitempty"`
+	PinCommit      string            `yaml:"pin_commit,omitempty" json:"pin_commit,omitempty"`
+	Commit         string            `yaml:"commit,omitempty" json:"commit,omitempty"`           // Commit recorded in the lock file (default: plan commit)
+	LastCommit     string            `yaml:"last_commit,omitempty" json:"last_commit,omitempty"` // Currently deployed commit
+	TreeHash       string            `yaml:"tree_hash,omitempty" json:"tree_hash,omitempty"`     // Content hash recorded in the lock file (tree mode)
+	Outputs        []string          `yaml:"outputs,omitempty" json:"outputs,omitempty"`         // Step outputs recorded in the lock file
+	IsLib          bool              `yaml:"is_lib,omitempty" json:"is_lib,omitempty"`
+	LockKey        string            `yaml:"lock_key,omitempty" json:"lock_key,omitempty"` // Lock entry of the target, if the artifact has several
+}
+
+// Key returns the key of the lock entry that the deployment updates
+func (a PlanArtifact) Key() string {
+	if a.LockKey != "" {
+		return a.LockKey
+	}
+	return a.Name
 }
 
 // PlanSkipped represents a skipped artifact
 type PlanSkipped struct {
-	Name   string `yaml:"name"`
-	Reason string `yaml:"reason"`
+	Name   string `yaml:"name" json:"name"`
+	Target string `yaml:"target,omitempty" json:"target,omitempty"` // Set if the artifact has several targets
+	Reason string `yaml:"reason" json:"reason"`
+}
+
+// PlanFingerprint identifies the inputs a plan was created from
+type PlanFingerprint struct {
+	Config  string            `yaml:"config" json:"config"`                       // Hash of the resolved config
+	Lock    string            `yaml:"lock,omitempty" json:"lock,omitempty"`       // Hash of the lock file contents
+	Presets map[string]string `yaml:"presets,omitempty" json:"presets,omitempty"` // Hashes of the presets used
 }
 
 // PlanFile is the serializable plan written to .bear/plan.yml
 type PlanFile struct {
-	CreatedAt  string         `yaml:"created_at"`
-	Commit     string         `yaml:"commit"`
-	Artifacts  []PlanArtifact `yaml:"artifacts"`
-	Skipped    []PlanSkipped  `yaml:"skipped,omitempty"`
-	Validated  int            `yaml:"validated"`
-	ToDeploy   int            `yaml:"to_deploy"`
-	TotalSkips int            `yaml:"total_skipped"`
+	FormatVersion int            `yaml:"format_version" json:"format_version"`
+	CreatedAt     string         `yaml:"created_at" json:"created_at"`
+	Commit        string         `yaml:"commit" json:"commit"`
+	Environment   string         `yaml:"environment,omitempty" json:"environment,omitempty"`
+	Artifacts     []PlanArtifact `yaml:"artifacts" json:"artifacts"`
+	Skipped       []PlanSkipped  `yaml:"skipped,omitempty" json:"skipped,omitempty"`
+	Validated     int            `yaml:"validated" json:"validated"`
+	Cached        int            `yaml:"cached,omitempty" json:"cached,omitempty"` // Validations taken from the cache
+	ToDeploy      int            `yaml:"to_deploy" json:"to_deploy"`
+	TotalSkips    int            `yaml:"total_skipped" json:"total_skipped"`
+	TotalChanges  int            `yaml:"total_changes,omitempty" json:"total_changes,omitempty"`
+
+	Fingerprint *PlanFingerprint `yaml:"fingerprint,omitempty" json:"fingerprint,omitempty"`
 }
 
 // NewPlanFile creates a new PlanFile with current timestamp
 func NewPlanFile(commit string) *PlanFile {
 	return &PlanFile{
-		CreatedAt: time.Now().UTC().Format(time.RFC3339),
-		Commit:    commit,
+		FormatVersion: PlanFormatVersion,
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+		Commit:        commit,
+		Artifacts:     []PlanArtifact{},
+	}
+}
+
+// EncodePlan serializes a plan as "yaml" or "json"
+func EncodePlan(plan *PlanFile, format string) ([]byte, error) {
+	switch format {
+	case "yaml":
+		return yaml.Marshal(plan)
+	case "json":
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown plan format: %s (use yaml or json)", format)
 	}
 }
 
@@ -66,7 +133,7 @@ func WritePlan(rootPath string, plan *PlanFile) error {
 		return err
 	}
 
-	data, err := yaml.Marshal(plan)
+	data, err := EncodePlan(plan, "yaml")
 	if err != nil {
 		return err
 	}
@@ -76,11 +143,32 @@ func WritePlan(rootPath string, plan *PlanFile) error {
 
 // ReadPlan reads the plan file from .bear/plan.yml
 func ReadPlan(rootPath string) (*PlanFile, error) {
-	data, err := os.ReadFile(PlanFilePath(rootPath))
+	return ReadPlanFile(PlanFilePath(rootPath))
+}
+
+// ReadPlanFile reads a plan file from the given path.
+// YAML is a superset of JSON, so plans saved with --output json are read as well.
+func ReadPlanFile(path string) (*PlanFile, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
+	// Check the version first, older plans may not decode
+	var header struct {
+		FormatVersion int `yaml:"format_version"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	if header.FormatVersion > PlanFormatVersion {
+		return nil, fmt.Errorf("plan format version %d is newer than supported version %d, upgrade bear",
+			header.FormatVersion, PlanFormatVersion)
+	}
+	if header.FormatVersion < PlanFormatVersion {
+		return nil, fmt.Errorf("plan format version %d is no longer supported, run bear plan again", header.FormatVersion)
+	}
+
 	var plan PlanFile
 	if err := yaml.Unmarshal(data, &plan); err != nil {
 		return nil, err
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncodePlan_RoundTrip(t *testing.T) {
+	plan := NewPlanFile("abc1234567890")
+	plan.Environment = "staging"
+	plan.Artifacts = append(plan.Artifacts, PlanArtifact{
+		Name:   "user-api",
+		Action: "deploy",
+		Steps:  []Step{{Name: "Deploy", Run: "gcloud run deploy"}},
+	})
+	plan.ToDeploy = 1
+
+	for _, format := range []string{"yaml", "json"} {
+		t.Run(format, func(t *testing.T) {
+			data, err := EncodePlan(plan, format)
+			if err != nil {
+				t.Fatalf("EncodePlan failed: %v", err)
+			}
+
+			path := filepath.Join(t.TempDir(), "plan."+format)
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatalf("failed to write plan: %v", err)
+			}
+
+			loaded, err := ReadPlanFile(path)
+			if err != nil {
+				t.Fatalf("ReadPlanFile failed: %v", err)
+			}
+			if loaded.FormatVersion != PlanFormatVersion {
+				t.Errorf("expected format version %d, got %d", PlanFormatVersion, loaded.FormatVersion)
+			}
+			if loaded.Environment != "staging" {
+				t.Errorf("expected environment 'staging', got '%s'", loaded.Environment)
+			}
+			if len(loaded.Artifacts) != 1 || loaded.Artifacts[0].Steps[0].Run != "gcloud run deploy" {
+				t.Errorf("unexpected artifacts: %+v", loaded.Artifacts)
+			}
+		})
+	}
+
+	if _, err := EncodePlan(plan, "toml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestReadPlanFile_NewerFormatVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.yml")
+	if err := os.WriteFile(path, []byte("format_version: 99\ncommit: abc\n"), 0644); err != nil {
+		t.Fatalf("failed to write plan: %v", err)
+	}
+
+	if _, err := ReadPlanFile(path); err == nil {
+		t.Error("expected error for newer format version")
+	}
+}
+
+func TestReadPlanFile_OlderFormatVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.yml")
+	plan := "format_version: 1\ncommit: abc\nartifacts:\n  - name: api\n    changed_files: [api/main.go]\n"
+	if err := os.WriteFile(path, []byte(plan), 0644); err != nil {
+		t.Fatalf("failed to write plan: %v", err)
+	}
+
+	_, err := ReadPlanFile(path)
+	if err == nil || !strings.Contains(err.Error(), "run bear plan again") {
+		t.Errorf("expected error for older format version, got %v", err)
+	}
+}
@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
-
-	"gopkg.in/yaml.v3"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 // Detection defines how a language is detected in a directory
@@ -14,23 +16,120 @@ type Detection struct {
 
 // Step defines a CI step
 type Step struct {
-	Name string `yaml:"name"`
-	Run  string `yaml:"run"`
+	Name            string `yaml:"name" json:"name"`
+	Run             string `yaml:"run" json:"run"`
+	If              string `yaml:"if,omitempty" json:"if,omitempty"`                               // Condition, the step is skipped if false
+	Timeout         string `yaml:"timeout,omitempty" json:"timeout,omitempty"`                     // Time limit per attempt, e.g. "10m"
+	Retries         int    `yaml:"retries,omitempty" json:"retries,omitempty"`                     // Extra attempts after a failure
+	ContinueOnError bool   `yaml:"continue_on_error,omitempty" json:"continue_on_error,omitempty"` // A failure does not fail the artifact
+}
+
+// TimeoutDuration parses the step timeout. Zero means no limit.
+func (s Step) TimeoutDuration() (time.Duration, error) {
+	if s.Timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s.Timeout)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid timeout '%s'", s.Timeout)
+	}
+	return d, nil
 }
 
 // Language defines a language with detection and validation rules
 type Language struct {
-	Name      string            `yaml:"-"` // Populated from map key
-	Detection Detection         `yaml:"detection"`
-	Vars      map[string]string `yaml:"vars,omitempty"` // Default variables for this language
-	Steps     []Step            `yaml:"steps"`          // Validation steps (e.g. lint, test, build)
+	Name      string             `yaml:"-"` // Populated from map key
+	Detection Detection          `yaml:"detection"`
+	Priority  int                `yaml:"priority,omitempty"` // Higher priorities win if several languages are detected
+	Vars      map[string]string  `yaml:"vars,omitempty"`     // Default variables for this language
+	Declare   map[string]VarDecl `yaml:"declare,omitempty"`  // Variables the steps use, checked before they run
+	Steps     []Step             `yaml:"steps"`              // Validation steps (e.g. lint, test, build)
 }
 
 // Target defines a reusable deployment template
 type Target struct {
-	Name  string            `yaml:"-"`              // Populated from map key
-	Vars  map[string]string `yaml:"vars,omitempty"` // Default variables for this target
-	Steps []Step            `yaml:"steps"`          // Deployment steps (with $VAR placeholders)
+	Name    string             `yaml:"-"`                 // Populated from map key
+	Vars    map[string]string  `yaml:"vars,omitempty"`    // Default variables for this target
+	Declare map[string]VarDecl `yaml:"declare,omitempty"` // Variables the steps use, checked before they run
+	Steps   []Step             `yaml:"steps"`             // Deployment steps (with $VAR placeholders)
+	Outputs []string           `yaml:"outputs,omitempty"` // Step outputs recorded in the lock file
+}
+
+// Environment defines a deployment environment with its own lock state
+type Environment struct {
+	Name    string            `yaml:"-"`                 // Populated from map key
+	Vars    map[string]string `yaml:"vars,omitempty"`    // Variable overrides for this environment
+	Secrets map[string]Secret `yaml:"secrets,omitempty"` // Secret overrides for this environment
+}
+
+// Secret defines where the value of a secret comes from. Secrets are
+// resolved at apply time and are never written to the plan.
+type Secret struct {
+	Env  string `yaml:"env,omitempty"`  // Environment variable holding the value
+	File string `yaml:"file,omitempty"` // File holding the value, relative to the workspace root
+}
+
+// Validate checks that exactly one source is set
+func (s Secret) Validate() error {
+	switch {
+	case s.Env == "" && s.File == "":
+		return fmt.Errorf("no source (set env or file)")
+	case s.Env != "" && s.File != "":
+		return fmt.Errorf("env and file are both set")
+	}
+	return nil
+}
+
+// Resolve reads the value of the secret. A trailing newline of a file is removed.
+func (s Secret) Resolve(rootPath string) (string, error) {
+	if err := s.Validate(); err != nil {
+		return "", err
+	}
+	if s.Env != "" {
+		value, ok := os.LookupEnv(s.Env)
+		if !ok || value == "" {
+			return "", fmt.Errorf("environment variable %s is not set", s.Env)
+		}
+		return value, nil
+	}
+
+	path := s.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(rootPath, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", s.File, err)
+	}
+	value := strings.TrimRight(string(data), "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("%s is empty", s.File)
+	}
+	return value, nil
+}
+
+// StateLock configures the advisory lock taken during plan and apply
+type StateLock struct {
+	Backend  string `yaml:"backend,omitempty"`   // "local" (default), "http" or "none"
+	URL      string `yaml:"url,omitempty"`       // Base URL for the http backend
+	TokenEnv string `yaml:"token_env,omitempty"` // Env var with a bearer token for the http backend
+	TTL      string `yaml:"ttl,omitempty"`       // Lock lifetime, e.g. "30m" (default: 1h); must exceed the longest apply
+}
+
+// Hooks are steps that run once per plan or apply in the workspace root
+type Hooks struct {
+	PrePlan   []Step `yaml:"pre_plan,omitempty"`   // Before change detection, a failure aborts the plan
+	PostPlan  []Step `yaml:"post_plan,omitempty"`  // After the plan, whether it succeeded or not
+	PreApply  []Step `yaml:"pre_apply,omitempty"`  // Before the first deployment, a failure aborts the apply
+	PostApply []Step `yaml:"post_apply,omitempty"` // After all deployments, whether they succeeded or not
+	OnFailure []Step `yaml:"on_failure,omitempty"` // After a failed plan or apply
+}
+
+// Cache configures where validation results are cached
+type Cache struct {
+	Backend  string `yaml:"backend,omitempty"`   // "local" (default), "http" or "none"
+	URL      string `yaml:"url,omitempty"`       // Base URL for the http backend
+	TokenEnv string `yaml:"token_env,omitempty"` // Env var with a bearer token for the http backend
 }
 
 // UseConfig defines which presets to import
@@ -39,23 +138,70 @@ type UseConfig struct {
 	Targets   []string `yaml:"targets,omitempty"`   // e.g. ["docker", "cloudrun", "lambda"]
 }
 
+// Change detection modes
+const (
+	ChangeDetectionDiff = "diff"
+	ChangeDetectionTree = "tree"
+)
+
 // Config is the main configuration (bear.config.yml)
 type Config struct {
-	Name      string              `yaml:"name"`
-	Use       UseConfig           `yaml:"use,omitempty"` // Import predefined presets
-	Languages map[string]Language `yaml:"languages"`
-	Targets   map[string]Target   `yaml:"targets,omitempty"`
+	Name         string                 `yaml:"name"`
+	Include      []string               `yaml:"include,omitempty"` // Files or globs with more languages, targets and vars
+	Use          UseConfig              `yaml:"use,omitempty"`     // Import predefined presets
+	Languages    map[string]Language    `yaml:"languages"`
+	Targets      map[string]Target      `yaml:"targets,omitempty"`
+	Vars         map[string]string      `yaml:"vars,omitempty"` // Project-wide default variables
+	Environments map[string]Environment `yaml:"environments,omitempty"`
+	StateLock    StateLock              `yaml:"state_lock,omitempty"`
+	Cache        Cache                  `yaml:"cache,omitempty"`
+	Hooks        Hooks                  `yaml:"hooks,omitempty"`
+
+	// Secrets are passed to deploy steps like vars, but resolved at apply
+	// time and masked in all output
+	Secrets map[string]Secret `yaml:"secrets,omitempty"`
+
+	// ChangeDetection selects how changes are detected: "diff" (default)
+	// compares commits, "tree" compares the content hashes of artifacts
+	ChangeDetection string `yaml:"change_detection,omitempty"`
+
+	// Templates enables ${{ ... }} expressions in vars and step commands,
+	// evaluated by bear plan
+	Templates bool `yaml:"templates,omitempty"`
+
+	// Executor runs the step commands: "shell" (default), "dry-run" or an
+	// executor registered by a custom build
+	Executor string `yaml:"executor,omitempty"`
+
+	Presets map[string]string `yaml:"-"` // Content hashes of resolved presets, e.g. "language/go"
+	Sources map[string]string `yaml:"-"` // File of each definition, see Source
 }
 
-// Load loads a bear.config.yml file
+// Load loads a bear.config.yml file and merges its includes
 func Load(path string) (*Config, error) {
+	cfg, err := loadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	base, main := filepath.Dir(path), filepath.Base(path)
+	cfg.recordSources(cfg, main)
+	if err := cfg.resolveIncludes(base, main); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadFile reads a config file without resolving its includes
+func loadFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := decodeStrict(path, data, &cfg); err != nil {
 		return nil, err
 	}
 
@@ -68,6 +214,10 @@ func Load(path string) (*Config, error) {
 		target.Name = name
 		cfg.Targets[name] = target
 	}
+	for name, env := range cfg.Environments {
+		env.Name = name
+		cfg.Environments[name] = env
+	}
 
 	return &cfg, nil
 }
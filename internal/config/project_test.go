@@ -107,3 +107,36 @@ func TestLoad_InvalidYAML(t *testing.T) {
 		t.Error("expected error for invalid YAML")
 	}
 }
+
+func TestSecret_Resolve(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "api.key"), []byte("file-value\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+	t.Setenv("BEAR_TEST_SECRET", "env-value")
+
+	tests := []struct {
+		name     string
+		secret   Secret
+		expected string
+		wantErr  bool
+	}{
+		{"env", Secret{Env: "BEAR_TEST_SECRET"}, "env-value", false},
+		{"file", Secret{File: "api.key"}, "file-value", false},
+		{"missing env", Secret{Env: "BEAR_TEST_UNSET_SECRET"}, "", true},
+		{"missing file", Secret{File: "missing.key"}, "", true},
+		{"no source", Secret{}, "", true},
+		{"two sources", Secret{Env: "BEAR_TEST_SECRET", File: "api.key"}, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.secret.Resolve(dir)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("%s: expected '%s', got '%s'", tt.name, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Schema file types
+const (
+	SchemaConfig   = "config"
+	SchemaArtifact = "artifact"
+	SchemaLibrary  = "lib"
+)
+
+// SchemaTypes lists the file types that have a JSON Schema
+var SchemaTypes = []string{SchemaConfig, SchemaArtifact, SchemaLibrary}
+
+// schemaFiles maps file types to their file names and Go types
+var schemaFiles = map[string]struct {
+	file string
+	typ  reflect.Type
+}{
+	SchemaConfig:   {"bear.config.yml", reflect.TypeOf(Config{})},
+	SchemaArtifact: {"bear.artifact.yml", reflect.TypeOf(Artifact{})},
+	SchemaLibrary:  {"bear.lib.yml", reflect.TypeOf(Library{})},
+}
+
+// schemaRequired lists the fields that must be set, by type
+var schemaRequired = map[reflect.Type][]string{
+	reflect.TypeOf(Config{}):   {"name"},
+	reflect.TypeOf(Artifact{}): {"name"},
+	reflect.TypeOf(Library{}):  {"name"},
+	reflect.TypeOf(Step{}):     {"name", "run"},
+}
+
+// schemaEnums lists the allowed values of fields, by "Type.Field"
+var schemaEnums = map[string][]string{
+	"Config.ChangeDetection": {ChangeDetectionDiff, ChangeDetectionTree},
+	"StateLock.Backend":      {"local", "http", "none"},
+	"Cache.Backend":          {"local", "http", "none"},
+	"VarDecl.Type":           {VarString, VarInt, VarBool, VarEnum},
+}
+
+// SchemaFileName returns the file name of a schema file type
+func SchemaFileName(fileType string) string {
+	return schemaFiles[fileType].file
+}
+
+// JSONSchema returns the JSON Schema of a file type. It is derived from
+// the same struct fields that the strict decoding accepts.
+func JSONSchema(fileType string) (map[string]any, error) {
+	f, ok := schemaFiles[fileType]
+	if !ok {
+		return nil, fmt.Errorf("unknown file type '%s' (expected one of: config, artifact, lib)", fileType)
+	}
+
+	g := &schemaGenerator{definitions: make(map[string]any)}
+	schema := g.structSchema(f.typ)
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = f.file
+	if len(g.definitions) > 0 {
+		schema["definitions"] = g.definitions
+	}
+	return schema, nil
+}
+
+// schemaGenerator collects the definitions of nested struct types
+type schemaGenerator struct {
+	definitions map[string]any
+}
+
+// typeSchema returns the schema of a value of type t
+func (g *schemaGenerator) typeSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if _, ok := g.definitions[t.Name()]; !ok {
+			g.definitions[t.Name()] = nil // Reserve the name for recursive types
+			g.definitions[t.Name()] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/definitions/" + t.Name()}
+	case reflect.Map:
+		elem := g.typeSchema(t.Elem())
+		if t.Elem().Kind() == reflect.String {
+			// YAML numbers and booleans are read as strings, e.g. vars
+			elem = map[string]any{"type": []string{"string", "number", "boolean"}}
+		}
+		return map[string]any{"type": "object", "additionalProperties": elem}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": g.typeSchema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	}
+	return map[string]any{}
+}
+
+// structSchema returns the schema of a struct, which allows no other fields
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := yamlName(f)
+		if name == "" {
+			continue
+		}
+		prop := g.typeSchema(f.Type)
+		if enum, ok := schemaEnums[t.Name()+"."+f.Name]; ok {
+			prop["enum"] = enum
+		}
+		properties[name] = prop
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if required := schemaRequired[t]; len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
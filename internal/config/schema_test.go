@@ -0,0 +1,69 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	tests := []struct {
+		fileType string
+		typ      reflect.Type
+	}{
+		{SchemaConfig, reflect.TypeOf(Config{})},
+		{SchemaArtifact, reflect.TypeOf(Artifact{})},
+		{SchemaLibrary, reflect.TypeOf(Library{})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileType, func(t *testing.T) {
+			schema, err := JSONSchema(tt.fileType)
+			if err != nil {
+				t.Fatalf("JSONSchema failed: %v", err)
+			}
+			if schema["additionalProperties"] != false {
+				t.Error("expected unknown fields to be disallowed")
+			}
+
+			// Every field accepted by the strict decoding is in the schema
+			properties := schema["properties"].(map[string]any)
+			fields := yamlFields(tt.typ)
+			if len(properties) != len(fields) {
+				t.Errorf("expected %d properties, got %d", len(fields), len(properties))
+			}
+			for name := range fields {
+				if _, ok := properties[name]; !ok {
+					t.Errorf("expected property '%s'", name)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONSchema_Definitions(t *testing.T) {
+	schema, err := JSONSchema(SchemaConfig)
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+
+	definitions := schema["definitions"].(map[string]any)
+	step, ok := definitions["Step"].(map[string]any)
+	if !ok {
+		t.Fatal("expected a Step definition")
+	}
+	if got := step["required"]; !reflect.DeepEqual(got, []string{"name", "run"}) {
+		t.Errorf("expected name and run to be required, got %v", got)
+	}
+
+	properties := schema["properties"].(map[string]any)
+	mode := properties["change_detection"].(map[string]any)
+	if !reflect.DeepEqual(mode["enum"], []string{"diff", "tree"}) {
+		t.Errorf("expected change_detection enum, got %v", mode["enum"])
+	}
+}
+
+func TestJSONSchema_UnknownType(t *testing.T) {
+	if _, err := JSONSchema("lock"); err == nil {
+		t.Error("expected an error for an unknown file type")
+	}
+}
@@ -0,0 +1,256 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PositionError is an error at a position in a YAML file
+type PositionError struct {
+	Path   string
+	Line   int
+	Column int // 0 if unknown
+	Msg    string
+}
+
+func (e *PositionError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Msg)
+}
+
+// yamlLineError matches the line prefix of yaml.v3 errors, e.g. "yaml: line 3: ..."
+var yamlLineError = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// decodeStrict decodes data into v and fails on fields that v does not
+// have and on values of the wrong kind. All problems are reported, each
+// with the file, line and column.
+func decodeStrict(path string, data []byte, v any) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return positionErrors(path, err)
+	}
+	if len(root.Content) == 0 {
+		return nil // Empty file
+	}
+
+	var errs []error
+	checkNode(path, root.Content[0], reflect.TypeOf(v).Elem(), &errs)
+	if len(errs) > 0 {
+		return errors.Join(uniqueErrors(errs)...)
+	}
+
+	if err := root.Content[0].Decode(v); err != nil {
+		return positionErrors(path, err)
+	}
+	return nil
+}
+
+// uniqueErrors drops repeated errors, e.g. of an anchor merged several times
+func uniqueErrors(errs []error) []error {
+	seen := make(map[string]bool, len(errs))
+	var unique []error
+	for _, err := range errs {
+		if !seen[err.Error()] {
+			seen[err.Error()] = true
+			unique = append(unique, err)
+		}
+	}
+	return unique
+}
+
+// positionErrors adds the file to the line numbers of yaml.v3 errors
+func positionErrors(path string, err error) error {
+	var lines []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		lines = typeErr.Errors
+	} else {
+		lines = []string{err.Error()}
+	}
+
+	errs := make([]error, 0, len(lines))
+	for _, line := range lines {
+		m := yamlLineError.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			errs = append(errs, fmt.Errorf("%s: %s", path, strings.TrimPrefix(line, "yaml: ")))
+			continue
+		}
+		n, _ := strconv.Atoi(m[1])
+		errs = append(errs, &PositionError{Path: path, Line: n, Msg: m[2]})
+	}
+	return errors.Join(errs...)
+}
+
+// checkNode checks that node can be decoded into a value of type t
+func checkNode(path string, node *yaml.Node, t reflect.Type, errs *[]error) {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return // Null leaves the zero value
+	}
+
+	fail := func(n *yaml.Node, format string, args ...any) {
+		*errs = append(*errs, &PositionError{Path: path, Line: n.Line, Column: n.Column, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			fail(node, "expected a mapping, got %s", describeNode(node))
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if isMergeKey(key) {
+				checkMerge(path, value, t, errs)
+				continue
+			}
+			field, ok := fields[key.Value]
+			if !ok {
+				msg := fmt.Sprintf("unknown field '%s'", key.Value)
+				if s := suggestField(key.Value, fields); s != "" {
+					msg += fmt.Sprintf(" (did you mean '%s'?)", s)
+				}
+				fail(key, "%s", msg)
+				continue
+			}
+			checkNode(path, value, field.Type, errs)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			fail(node, "expected a mapping, got %s", describeNode(node))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if isMergeKey(node.Content[i]) {
+				checkMerge(path, node.Content[i+1], t, errs)
+				continue
+			}
+			checkNode(path, node.Content[i+1], t.Elem(), errs)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			fail(node, "expected a list, got %s", describeNode(node))
+			return
+		}
+		for _, item := range node.Content {
+			checkNode(path, item, t.Elem(), errs)
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			fail(node, "expected a string, got %s", describeNode(node))
+		}
+	case reflect.Int, reflect.Int64:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!int" {
+			fail(node, "expected an integer, got %s", describeNode(node))
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!bool" {
+			fail(node, "expected true or false, got %s", describeNode(node))
+		}
+	}
+}
+
+// isMergeKey reports whether a mapping key is the merge key "<<"
+func isMergeKey(key *yaml.Node) bool {
+	return key.Kind == yaml.ScalarNode && key.ShortTag() == "!!merge"
+}
+
+// checkMerge checks the mappings merged with "<<" into a value of type t.
+// Like yaml.v3, it accepts a mapping or a list of mappings, usually aliases.
+func checkMerge(path string, value *yaml.Node, t reflect.Type, errs *[]error) {
+	for value.Kind == yaml.AliasNode {
+		value = value.Alias
+	}
+	if value.Kind == yaml.SequenceNode {
+		for _, item := range value.Content {
+			checkNode(path, item, t, errs)
+		}
+		return
+	}
+	checkNode(path, value, t, errs)
+}
+
+// yamlFields returns the fields of a struct type by their YAML name
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := yamlName(f)
+		if name != "" {
+			fields[name] = f
+		}
+	}
+	return fields
+}
+
+// yamlName returns the YAML name of a struct field, "" if it is not decoded
+func yamlName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return strings.ToLower(f.Name)
+	}
+	return name
+}
+
+// describeNode describes the kind of a node for error messages
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return fmt.Sprintf("'%s'", node.Value)
+}
+
+// suggestField returns the known field closest to name, if it is close enough
+func suggestField(name string, fields map[string]reflect.StructField) string {
+	best, bestDist := "", 3
+	for _, field := range sortedNames(fields) {
+		if d := editDistance(name, field); d < bestDist {
+			best, bestDist = field, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadArtifact_Strict(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "valid",
+			content: "name: api\ntarget: docker\nvars:\n  PORT: 8080\n",
+		},
+		{
+			name:    "unknown field",
+			content: "name: api\ntaget: docker\n",
+			want:    []string{"bear.artifact.yml:2:1: unknown field 'taget' (did you mean 'target'?)"},
+		},
+		{
+			name:    "several problems",
+			content: "name: api\ntarget: docker\ndepend: [db]\nvars:\n  - PORT\n",
+			want: []string{
+				"bear.artifact.yml:3:1: unknown field 'depend' (did you mean 'depends'?)",
+				"bear.artifact.yml:5:3: expected a mapping, got a list",
+			},
+		},
+		{
+			name:    "no suggestion",
+			content: "name: api\nregion: eu\n",
+			want:    []string{"bear.artifact.yml:2:1: unknown field 'region'\n"},
+		},
+		{
+			name:    "syntax error",
+			content: "name: api\n\ttarget: docker\n",
+			want:    []string{"bear.artifact.yml:2: found a tab character"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bear.artifact.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write artifact: %v", err)
+			}
+
+			_, err := LoadArtifact(path)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error()+"\n", want) {
+					t.Errorf("expected error to contain '%s', got:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_StrictNested(t *testing.T) {
+	content := `name: test
+targets:
+  docker:
+    steps:
+      - name: Deploy
+        run: deploy
+        retries: often
+        continue_on_eror: true
+`
+	path := filepath.Join(t.TempDir(), "bear.config.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"bear.config.yml:7:18: expected an integer, got 'often'",
+		"bear.config.yml:8:9: unknown field 'continue_on_eror' (did you mean 'continue_on_error'?)",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain '%s', got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoad_StrictMergeKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name: "merged target",
+			content: `name: test
+targets:
+  cloudrun: &base
+    steps:
+      - name: Deploy
+        run: deploy
+    vars:
+      REGION: eu
+  cloudrun-job:
+    <<: *base
+    vars:
+      <<: {REGION: us}
+      JOB: "true"
+`,
+		},
+		{
+			name: "unknown field in merged list",
+			content: `name: test
+targets:
+  cloudrun: &base
+    stepz: []
+  cloudrun-job:
+    <<: [*base]
+`,
+			want: []string{"bear.config.yml:4:5: unknown field 'stepz' (did you mean 'steps'?)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bear.config.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			cfg, err := Load(path)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				job := cfg.Targets["cloudrun-job"]
+				if len(job.Steps) != 1 || job.Vars["REGION"] != "us" || job.Vars["JOB"] != "true" {
+					t.Errorf("expected merged target, got %+v", job)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected error to contain '%s', got:\n%v", want, err)
+				}
+			}
+			if n := strings.Count(err.Error(), "\n") + 1; n != len(tt.want) {
+				t.Errorf("expected %d errors, got:\n%v", len(tt.want), err)
+			}
+		})
+	}
+}
+
+func TestLoadLibrary_Strict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bear.lib.yml")
+	if err := os.WriteFile(path, []byte("name: common\ntarget: docker\n"), 0644); err != nil {
+		t.Fatalf("failed to write library: %v", err)
+	}
+
+	_, err := LoadLibrary(path)
+	if err == nil || !strings.Contains(err.Error(), "bear.lib.yml:2:1: unknown field 'target'") {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Var types
+const (
+	VarString = "string"
+	VarInt    = "int"
+	VarBool   = "bool"
+	VarEnum   = "enum"
+)
+
+// VarDecl declares a variable that a language or target uses
+type VarDecl struct {
+	Required    bool     `yaml:"required,omitempty"`    // The var must be set and not empty
+	Default     string   `yaml:"default,omitempty"`     // Used if no vars set the var
+	Type        string   `yaml:"type,omitempty"`        // "string" (default), "int", "bool" or "enum"
+	Values      []string `yaml:"values,omitempty"`      // Allowed values of an enum
+	Pattern     string   `yaml:"pattern,omitempty"`     // Regular expression the whole value must match
+	Description string   `yaml:"description,omitempty"` // Shown in errors about the var
+}
+
+// Validate checks the declaration itself, including its default
+func (d VarDecl) Validate() error {
+	switch d.Type {
+	case "", VarString, VarInt, VarBool:
+		if len(d.Values) > 0 {
+			return fmt.Errorf("values are only allowed for type enum")
+		}
+	case VarEnum:
+		if len(d.Values) == 0 {
+			return fmt.Errorf("type enum needs values")
+		}
+	default:
+		return fmt.Errorf("unknown type '%s' (expected string, int, bool or enum)", d.Type)
+	}
+	if _, err := d.pattern(); err != nil {
+		return err
+	}
+	if d.Default != "" {
+		if err := d.Check(d.Default); err != nil {
+			return fmt.Errorf("invalid default: %w", err)
+		}
+	}
+	return nil
+}
+
+// Check verifies that value is valid for the declaration. Empty values
+// are only checked for required vars.
+func (d VarDecl) Check(value string) error {
+	if value == "" {
+		if d.Required {
+			return fmt.Errorf("required but not set%s", d.describe())
+		}
+		return nil
+	}
+
+	switch d.Type {
+	case VarInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("'%s' is not an integer%s", value, d.describe())
+		}
+	case VarBool:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("'%s' is not true or false%s", value, d.describe())
+		}
+	case VarEnum:
+		if !contains(d.Values, value) {
+			return fmt.Errorf("'%s' is not one of %s%s", value, strings.Join(d.Values, ", "), d.describe())
+		}
+	}
+
+	re, err := d.pattern()
+	if err != nil {
+		return err
+	}
+	if re != nil && !re.MatchString(value) {
+		return fmt.Errorf("'%s' does not match %s%s", value, d.Pattern, d.describe())
+	}
+	return nil
+}
+
+// pattern compiles the pattern so that it must match the whole value
+func (d VarDecl) pattern() (*regexp.Regexp, error) {
+	if d.Pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile("^(?:" + d.Pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %w", d.Pattern, err)
+	}
+	return re, nil
+}
+
+// describe returns the description for error messages
+func (d VarDecl) describe() string {
+	if d.Description == "" {
+		return ""
+	}
+	return " (" + d.Description + ")"
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVarDecl_Check(t *testing.T) {
+	tests := []struct {
+		name  string
+		decl  VarDecl
+		value string
+		want  string // Part of the error, "" for none
+	}{
+		{"optional empty", VarDecl{Type: VarInt}, "", ""},
+		{"required empty", VarDecl{Required: true, Description: "GCP project"}, "", "required but not set (GCP project)"},
+		{"string", VarDecl{}, "anything", ""},
+		{"int", VarDecl{Type: VarInt}, "42", ""},
+		{"invalid int", VarDecl{Type: VarInt}, "4.2", "'4.2' is not an integer"},
+		{"bool", VarDecl{Type: VarBool}, "false", ""},
+		{"invalid bool", VarDecl{Type: VarBool}, "yes", "'yes' is not true or false"},
+		{"enum", VarDecl{Type: VarEnum, Values: []string{"free", "pro"}}, "pro", ""},
+		{"invalid enum", VarDecl{Type: VarEnum, Values: []string{"free", "pro"}}, "gold", "'gold' is not one of free, pro"},
+		{"pattern", VarDecl{Pattern: "[a-z][a-z0-9-]+"}, "acme-prod", ""},
+		{"pattern matches whole value", VarDecl{Pattern: "[a-z]+"}, "acme-prod", "does not match [a-z]+"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.decl.Check(tt.value)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing '%s', got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestVarDecl_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		decl VarDecl
+		want string // Part of the error, "" for none
+	}{
+		{"plain", VarDecl{Required: true}, ""},
+		{"enum", VarDecl{Type: VarEnum, Values: []string{"a"}, Default: "a"}, ""},
+		{"unknown type", VarDecl{Type: "float"}, "unknown type 'float'"},
+		{"enum without values", VarDecl{Type: VarEnum}, "type enum needs values"},
+		{"values without enum", VarDecl{Values: []string{"a"}}, "only allowed for type enum"},
+		{"invalid pattern", VarDecl{Pattern: "[a-"}, "invalid pattern"},
+		{"invalid default", VarDecl{Type: VarInt, Default: "many"}, "invalid default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.decl.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing '%s', got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -2,14 +2,18 @@ package internal
 
 import (
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
+
+	"github.com/irevolve/bear/internal/config"
 )
 
 // ChangedFile represents a changed file
 type ChangedFile struct {
-	Path   string
-	Status string // A=Added, M=Modified, D=Deleted, R=Renamed
+	Path    string
+	Status  string // A=Added, M=Modified, D=Deleted, R=Renamed, C=Copied
+	OldPath string // Source path of renames and copies
 }
 
 // getGitRoot returns the root directory of the Git repository
@@ -25,7 +29,7 @@ func getGitRoot(path string) string {
 
 // GetChangedFilesBetweenCommits returns changed files between two commits
 func GetChangedFilesBetweenCommits(rootPath string, fromCommit, toCommit string) ([]ChangedFile, error) {
-	cmd := exec.Command("git", "diff", "--name-status", "--ignore-space-change", "--ignore-blank-lines", fromCommit+".."+toCommit)
+	cmd := exec.Command("git", "diff", "--name-status", "-z", "--ignore-space-change", "--ignore-blank-lines", fromCommit+".."+toCommit)
 	cmd.Dir = rootPath
 
 	output, err := cmd.Output()
@@ -49,13 +53,39 @@ func GetCurrentCommit(rootPath string) string {
 	return strings.TrimSpace(string(output))
 }
 
+// GetCurrentBranch returns the checked out branch, or "" for a detached HEAD
+func GetCurrentBranch(rootPath string) string {
+	cmd := exec.Command("git", "symbolic-ref", "--short", "-q", "HEAD")
+	cmd.Dir = rootPath
+
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(output))
+}
+
+// GetCommitTime returns the committer date of a commit in RFC 3339 format
+func GetCommitTime(rootPath, commit string) string {
+	cmd := exec.Command("git", "show", "-s", "--format=%cI", commit, "--")
+	cmd.Dir = rootPath
+
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(output))
+}
+
 // GetUncommittedChanges returns all uncommitted and untracked files
 func GetUncommittedChanges(rootPath string) ([]ChangedFile, error) {
 	gitRoot := getGitRoot(rootPath)
 	var allFiles []ChangedFile
 
 	// 1. Staged changes
-	cmd := exec.Command("git", "diff", "--name-status", "--cached", "--ignore-space-change", "--ignore-blank-lines")
+	cmd := exec.Command("git", "diff", "--name-status", "-z", "--cached", "--ignore-space-change", "--ignore-blank-lines")
 	cmd.Dir = rootPath
 	output, err := cmd.Output()
 	if err != nil {
@@ -66,7 +96,7 @@ func GetUncommittedChanges(rootPath string) ([]ChangedFile, error) {
 	}
 
 	// 2. Unstaged changes
-	cmd = exec.Command("git", "diff", "--name-status", "--ignore-space-change", "--ignore-blank-lines")
+	cmd = exec.Command("git", "diff", "--name-status", "-z", "--ignore-space-change", "--ignore-blank-lines")
 	cmd.Dir = rootPath
 	output, err = cmd.Output()
 	if err != nil {
@@ -76,15 +106,15 @@ func GetUncommittedChanges(rootPath string) ([]ChangedFile, error) {
 	}
 
 	// 3. Untracked files
-	cmd = exec.Command("git", "ls-files", "--others", "--exclude-standard")
+	cmd = exec.Command("git", "ls-files", "-z", "--others", "--exclude-standard")
 	cmd.Dir = rootPath
 	output, err = cmd.Output()
 	if err != nil {
 		Warn("failed to get untracked files", "error", err)
 	} else {
-		for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
-			if line != "" {
-				allFiles = append(allFiles, ChangedFile{Status: "A", Path: line})
+		for _, path := range strings.Split(string(output), "\x00") {
+			if path != "" {
+				allFiles = append(allFiles, ChangedFile{Status: "A", Path: path})
 			}
 		}
 	}
@@ -102,11 +132,29 @@ func GetUncommittedChanges(rootPath string) ([]ChangedFile, error) {
 	for _, f := range allFiles {
 		if workspacePrefix == "" {
 			filteredFiles = append(filteredFiles, f)
-		} else if strings.HasPrefix(f.Path, workspacePrefix) {
+			continue
+		}
+
+		// Renames across the workspace boundary keep the side inside it
+		inside := strings.HasPrefix(f.Path, workspacePrefix)
+		oldInside := f.OldPath != "" && strings.HasPrefix(f.OldPath, workspacePrefix)
+		switch {
+		case inside && oldInside:
+			filteredFiles = append(filteredFiles, ChangedFile{
+				Status:  f.Status,
+				Path:    strings.TrimPrefix(f.Path, workspacePrefix),
+				OldPath: strings.TrimPrefix(f.OldPath, workspacePrefix),
+			})
+		case inside:
 			filteredFiles = append(filteredFiles, ChangedFile{
 				Status: f.Status,
 				Path:   strings.TrimPrefix(f.Path, workspacePrefix),
 			})
+		case oldInside:
+			filteredFiles = append(filteredFiles, ChangedFile{
+				Status: "D",
+				Path:   strings.TrimPrefix(f.OldPath, workspacePrefix),
+			})
 		}
 	}
 
@@ -123,22 +171,40 @@ func GetUncommittedChanges(rootPath string) ([]ChangedFile, error) {
 	return uniqueFiles, nil
 }
 
+// parseGitDiff parses the output of 'git diff --name-status -z'. Records
+// are NUL-separated: the status followed by one path, or by the old and the
+// new path for renames and copies.
 func parseGitDiff(output string) []ChangedFile {
 	var files []ChangedFile
-	lines := strings.Split(strings.TrimSpace(output), "\n")
+	fields := strings.Split(output, "\x00")
 
-	for _, line := range lines {
-		if line == "" {
+	for i := 0; i < len(fields); i++ {
+		status := fields[i]
+		if status == "" {
 			continue
 		}
 
-		parts := strings.Fields(line)
-		if len(parts) >= 2 {
+		if strings.HasPrefix(status, "R") || strings.HasPrefix(status, "C") {
+			if i+2 >= len(fields) {
+				break
+			}
 			files = append(files, ChangedFile{
-				Status: parts[0],
-				Path:   parts[len(parts)-1],
+				Status:  status,
+				OldPath: fields[i+1],
+				Path:    fields[i+2],
 			})
+			i += 2
+			continue
 		}
+
+		if i+1 >= len(fields) {
+			break
+		}
+		files = append(files, ChangedFile{
+			Status: status,
+			Path:   fields[i+1],
+		})
+		i++
 	}
 
 	return files
@@ -159,3 +225,52 @@ func GetAffectedDirs(files []ChangedFile) map[string]bool {
 
 	return dirs
 }
+
+// changeIndex indexes changed files by the directories that contain them,
+// so the files below an artifact directory can be found with a map lookup.
+type changeIndex struct {
+	files []ChangedFile
+	byDir map[string][]int // Directory → indices into files, in diff order
+}
+
+// newChangeIndex builds an index over files. Renames and copies are indexed
+// under the directories of both their old and their new path.
+func newChangeIndex(files []ChangedFile) *changeIndex {
+	idx := &changeIndex{
+		files: files,
+		byDir: make(map[string][]int),
+	}
+
+	for i, f := range files {
+		seen := make(map[string]bool)
+		for _, p := range []string{f.Path, f.OldPath} {
+			for dir := p; dir != ""; dir = path.Dir(dir) {
+				if !seen[dir] {
+					seen[dir] = true
+					idx.byDir[dir] = append(idx.byDir[dir], i)
+				}
+				if dir == "." || dir == "/" {
+					break
+				}
+			}
+		}
+	}
+
+	return idx
+}
+
+// affected returns the changed files attributed to an artifact. Only files
+// below the artifact directory are checked, unless watch rules may match
+// files anywhere in the workspace.
+func (idx *changeIndex) affected(rules changeRules) (bool, []config.AffectedFile) {
+	if len(rules.watch) > 0 {
+		return isArtifactAffected(rules, idx.files)
+	}
+
+	indices := idx.byDir[rules.path]
+	candidates := make([]ChangedFile, len(indices))
+	for i, j := range indices {
+		candidates[i] = idx.files[j]
+	}
+	return isArtifactAffected(rules, candidates)
+}
@@ -2,6 +2,8 @@ package internal
 
 import (
 	"testing"
+
+	"github.com/irevolve/bear/internal/config"
 )
 
 func TestParseGitDiff(t *testing.T) {
@@ -17,30 +19,28 @@ func TestParseGitDiff(t *testing.T) {
 		},
 		{
 			name:  "single added file",
-			input: "A\tpath/to/file.go",
+			input: "A\x00path/to/file.go\x00",
 			expected: []ChangedFile{
 				{Status: "A", Path: "path/to/file.go"},
 			},
 		},
 		{
 			name:  "single modified file",
-			input: "M\tpath/to/file.go",
+			input: "M\x00path/to/file.go\x00",
 			expected: []ChangedFile{
 				{Status: "M", Path: "path/to/file.go"},
 			},
 		},
 		{
 			name:  "single deleted file",
-			input: "D\tpath/to/file.go",
+			input: "D\x00path/to/file.go\x00",
 			expected: []ChangedFile{
 				{Status: "D", Path: "path/to/file.go"},
 			},
 		},
 		{
-			name: "multiple files",
-			input: `A	new-file.go
-M	modified-file.go
-D	deleted-file.go`,
+			name:  "multiple files",
+			input: "A\x00new-file.go\x00M\x00modified-file.go\x00D\x00deleted-file.go\x00",
 			expected: []ChangedFile{
 				{Status: "A", Path: "new-file.go"},
 				{Status: "M", Path: "modified-file.go"},
@@ -49,20 +49,32 @@ D	deleted-file.go`,
 		},
 		{
 			name:  "renamed file",
-			input: "R100\told/path.go\tnew/path.go",
+			input: "R100\x00old/path.go\x00new/path.go\x00",
 			expected: []ChangedFile{
-				{Status: "R100", Path: "new/path.go"},
+				{Status: "R100", Path: "new/path.go", OldPath: "old/path.go"},
 			},
 		},
 		{
-			name: "with empty lines",
-			input: `A	file1.go
-
-M	file2.go
-`,
+			name:  "copied file followed by modification",
+			input: "C075\x00src/a.go\x00src/b.go\x00M\x00src/c.go\x00",
+			expected: []ChangedFile{
+				{Status: "C075", Path: "src/b.go", OldPath: "src/a.go"},
+				{Status: "M", Path: "src/c.go"},
+			},
+		},
+		{
+			name:  "paths with spaces and unicode",
+			input: "M\x00docs/my file.md\x00R090\x00services/äpi/main.go\x00services/api v2/main.go\x00",
 			expected: []ChangedFile{
-				{Status: "A", Path: "file1.go"},
-				{Status: "M", Path: "file2.go"},
+				{Status: "M", Path: "docs/my file.md"},
+				{Status: "R090", Path: "services/api v2/main.go", OldPath: "services/äpi/main.go"},
+			},
+		},
+		{
+			name:  "truncated record",
+			input: "M\x00file1.go\x00R100\x00old.go",
+			expected: []ChangedFile{
+				{Status: "M", Path: "file1.go"},
 			},
 		},
 	}
@@ -82,6 +94,9 @@ M	file2.go
 				if f.Path != tt.expected[i].Path {
 					t.Errorf("file %d: expected path '%s', got '%s'", i, tt.expected[i].Path, f.Path)
 				}
+				if f.OldPath != tt.expected[i].OldPath {
+					t.Errorf("file %d: expected old path '%s', got '%s'", i, tt.expected[i].OldPath, f.OldPath)
+				}
 			}
 		})
 	}
@@ -155,3 +170,66 @@ func TestGetAffectedDirs(t *testing.T) {
 		})
 	}
 }
+
+func TestChangeIndex(t *testing.T) {
+	idx := newChangeIndex([]ChangedFile{
+		{Status: "M", Path: "services/api/main.go"},
+		{Status: "M", Path: "services/api-gateway/main.go"},
+		{Status: "R100", Path: "libs/common/util.go", OldPath: "services/api/util.go"},
+		{Status: "M", Path: "proto/user.proto"},
+		{Status: "M", Path: "README.md"},
+	})
+
+	tests := []struct {
+		name     string
+		rules    changeRules
+		expected []config.AffectedFile
+	}{
+		{
+			name:  "files below artifact directory",
+			rules: changeRules{path: "services/api"},
+			expected: []config.AffectedFile{
+				{Path: "services/api/main.go"},
+				{Path: "services/api/util.go", Reason: "moved to libs/common/util.go"},
+			},
+		},
+		{
+			name:     "sibling with common prefix",
+			rules:    changeRules{path: "services/api-gateway"},
+			expected: []config.AffectedFile{{Path: "services/api-gateway/main.go"}},
+		},
+		{
+			name:     "rename target",
+			rules:    changeRules{path: "libs/common"},
+			expected: []config.AffectedFile{{Path: "libs/common/util.go"}},
+		},
+		{
+			name:     "watch outside artifact directory",
+			rules:    changeRules{path: "services/web", watch: []string{"../../proto"}},
+			expected: []config.AffectedFile{{Path: "proto/user.proto", Reason: "watch: ../../proto"}},
+		},
+		{
+			name:     "unchanged artifact",
+			rules:    changeRules{path: "services/web"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			affected, files := idx.affected(tt.rules)
+
+			if affected != (len(tt.expected) > 0) {
+				t.Errorf("expected affected=%v, got %v", len(tt.expected) > 0, affected)
+			}
+			if len(files) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, files)
+			}
+			for i := range files {
+				if files[i] != tt.expected[i] {
+					t.Errorf("expected '%s', got '%s'", tt.expected[i], files[i])
+				}
+			}
+		})
+	}
+}
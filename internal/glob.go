@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchGlob reports whether name matches the slash-separated pattern.
+// Segments use path.Match syntax; a "**" segment matches zero or more
+// directories.
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Trailing ** matches everything below
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// matchPathOrParent reports whether name or one of its parent directories
+// matches the pattern. A plain directory pattern therefore covers all files
+// below it.
+func matchPathOrParent(pattern, name string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	for name != "." && name != "/" && name != "" {
+		if MatchGlob(pattern, name) {
+			return true
+		}
+		name = path.Dir(name)
+	}
+	return false
+}
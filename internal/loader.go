@@ -2,13 +2,15 @@ package internal
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/irevolve/bear/internal/config"
 )
 
-// Load loads a config and resolves all presets
-func Load(path string) (*config.Config, error) {
-	cfg, err := config.Load(path)
+// Load loads a config with its includes and resolves all presets. The
+// overlay files are applied on top, in order.
+func Load(path string, overlays []string) (*config.Config, error) {
+	cfg, err := LoadLocal(path, overlays)
 	if err != nil {
 		return nil, err
 	}
@@ -26,6 +28,23 @@ func Load(path string) (*config.Config, error) {
 	return cfg, nil
 }
 
+// LoadLocal loads a config with its includes and overlays, without resolving presets
+func LoadLocal(path string, overlays []string) (*config.Config, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, overlay := range overlays {
+		if err := cfg.ApplyOverlay(overlay); err != nil {
+			return nil, err
+		}
+		Debug("applied config overlay", "file", overlay)
+	}
+
+	return cfg, nil
+}
+
 // resolveLanguages adds language presets from remote
 func resolveLanguages(cfg *config.Config) error {
 	if len(cfg.Use.Languages) == 0 {
@@ -48,6 +67,9 @@ func resolveLanguages(cfg *config.Config) error {
 			}
 			preset.Name = name
 			cfg.Languages[name] = preset
+			if err := recordPreset(cfg, "language/"+name, preset); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -76,8 +98,26 @@ func resolveTargets(cfg *config.Config) error {
 			}
 			preset.Name = name
 			cfg.Targets[name] = preset
+			if err := recordPreset(cfg, "target/"+name, preset); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
+
+// recordPreset stores the content hash of a resolved preset in the config
+func recordPreset(cfg *config.Config, key string, preset any) error {
+	hash, err := config.Hash(preset)
+	if err != nil {
+		return err
+	}
+	if cfg.Presets == nil {
+		cfg.Presets = make(map[string]string)
+	}
+	cfg.Presets[key] = hash
+	kind, name, _ := strings.Cut(key, "/")
+	cfg.SetSource(kind, name, config.SourcePreset)
+	return nil
+}
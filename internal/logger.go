@@ -18,7 +18,7 @@ func init() {
 	SetupLogger(false)
 }
 
-// SetupLogger configures the global logger
+// SetupLogger configures the global logger. Secret values are masked.
 func SetupLogger(verbose bool) {
 	Verbose = verbose
 
@@ -27,7 +27,7 @@ func SetupLogger(verbose bool) {
 		level = slog.LevelDebug
 	}
 
-	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+	handler := slog.NewTextHandler(RedactWriter(os.Stderr), &slog.HandlerOptions{
 		Level: level,
 	})
 	Logger = slog.New(handler)
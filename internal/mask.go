@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// secretMask is the replacement for secret values in output
+const secretMask = "***"
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string // Longest first, so that overlapping values are fully masked
+)
+
+// minMaskedLine is the shortest line of a multi-line secret that is masked
+// on its own. Shorter lines like "{" or "}" would mask unrelated output.
+const minMaskedLine = 8
+
+// MaskSecrets registers values that are masked in everything the logger
+// and the CLI output emit. Lines of a multi-line value are also masked on
+// their own, since output is often processed line by line, unless they are
+// short or PEM armor like "-----END PRIVATE KEY-----".
+func MaskSecrets(values ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	known := make(map[string]bool, len(secrets))
+	for _, s := range secrets {
+		known[s] = true
+	}
+	add := func(v string) {
+		if v != "" && !known[v] {
+			known[v] = true
+			secrets = append(secrets, v)
+		}
+	}
+	for _, v := range values {
+		add(v)
+		if strings.Contains(v, "\n") {
+			for _, line := range strings.Split(v, "\n") {
+				if line = strings.TrimSpace(line); maskableLine(line) {
+					add(line)
+				}
+			}
+		}
+	}
+	sort.SliceStable(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+}
+
+// maskableLine reports whether a line of a multi-line secret is specific
+// enough to be masked on its own
+func maskableLine(line string) bool {
+	return len(line) >= minMaskedLine && !strings.HasPrefix(line, "-----")
+}
+
+// ResetSecrets forgets all registered secrets (for tests)
+func ResetSecrets() {
+	secretsMu.Lock()
+	secrets = nil
+	secretsMu.Unlock()
+}
+
+// Redact replaces all registered secret values in s
+func Redact(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, secret := range secrets {
+		if strings.Contains(s, secret) {
+			s = strings.ReplaceAll(s, secret, secretMask)
+		}
+	}
+	return s
+}
+
+// RedactWriter returns a writer that masks secrets before writing to w.
+// Values split across two writes are not masked, so callers should write
+// whole lines.
+func RedactWriter(w io.Writer) io.Writer {
+	return redactWriter{w}
+}
+
+type redactWriter struct {
+	w io.Writer
+}
+
+func (r redactWriter) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(r.w, Redact(string(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"fmt"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -18,12 +20,16 @@ const (
 
 // PlannedAction represents a planned action
 type PlannedAction struct {
-	Artifact     DiscoveredArtifact
-	Action       ActionType
-	Reason       string
-	Steps        []config.Step
-	ChangedFiles []string
-	PinCommit    string // If set, this commit will be deployed (pin)
+	Artifact       DiscoveredArtifact
+	Action         ActionType
+	Target         string // Target of the action, "" for libraries and artifacts with several targets
+	Reason         string
+	Steps          []config.Step
+	ChangedFiles   []config.AffectedFile
+	ChangesUnknown bool   // Changed files are unknown (new artifact, dependency, pin, ...)
+	PinCommit      string // If set, this commit will be deployed (pin)
+	Commit         string // If set, this commit is deployed without pinning (promote)
+	TreeHash       string // Content hash at HEAD, recorded on deploy in tree mode
 }
 
 // Plan contains all planned actions
@@ -35,13 +41,17 @@ type Plan struct {
 	ToSkip       int
 	LockFile     *config.LockFile
 	LockPath     string
+	Artifacts    []DiscoveredArtifact // All discovered artifacts, before filtering
 }
 
 // PlanOptions contains options for plan creation
 type PlanOptions struct {
-	Artifacts []string // Only consider these artifacts
-	PinCommit string   // Pin to this commit
-	Force     bool     // Ignore pinned artifacts
+	Selector    Selector // Only consider the selected artifacts
+	PinCommit   string   // Pin to this commit
+	PinTarget   string   // Only pin this target ("" = all targets)
+	Force       bool     // Ignore pinned artifacts
+	Environment string   // Environment whose lock state is used ("" = default)
+	PromoteFrom string   // Deploy the commits recorded in this environment's lock
 }
 
 // getValidationSteps returns all validation steps for a given language
@@ -52,29 +62,75 @@ func getValidationSteps(cfg *config.Config, language string) []config.Step {
 	return nil
 }
 
+// deploySteps returns the deploy steps of a target
+func deploySteps(cfg *config.Config, target string) []config.Step {
+	if t, ok := cfg.Targets[target]; ok {
+		return t.Steps
+	}
+	return nil
+}
+
+// deployUnit is the deployment of an artifact to one of its targets.
+// Every unit has its own entry in the lock file.
+type deployUnit struct {
+	target string
+	key    string // Key of the lock entry
+}
+
+// deployUnits returns the deploy units of an artifact. Libraries and
+// artifacts without a target have a single unit without a target.
+func deployUnits(artifact *config.Artifact) []deployUnit {
+	targets := artifact.TargetNames()
+	if artifact.IsLib || len(targets) == 0 {
+		return []deployUnit{{key: artifact.Name}}
+	}
+	units := make([]deployUnit, len(targets))
+	for i, target := range targets {
+		units[i] = deployUnit{target: target, key: artifact.LockKey(target)}
+	}
+	return units
+}
+
 // CreatePlanWithOptions creates a plan with extended options
 func CreatePlanWithOptions(rootPath string, cfg *config.Config, opts PlanOptions) (*Plan, error) {
+	switch cfg.ChangeDetection {
+	case "", config.ChangeDetectionDiff, config.ChangeDetectionTree:
+	default:
+		return nil, fmt.Errorf("change_detection: unknown mode '%s' (use diff or tree)", cfg.ChangeDetection)
+	}
+
 	// Load lock file
-	lockPath := filepath.Join(rootPath, "bear.lock.yml")
+	lockPath := config.LockFilePath(rootPath, opts.Environment)
 	lockFile, err := config.LoadLock(lockPath)
 	if err != nil {
 		return nil, err
 	}
 
 	// Scan all artifacts
-	artifacts, err := ScanArtifacts(rootPath, cfg)
+	all, err := ScanArtifacts(rootPath, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	// Filter artifacts if Artifacts are specified
-	if len(opts.Artifacts) > 0 {
-		artifacts = filterArtifacts(artifacts, opts.Artifacts)
-	}
+	// Filter artifacts if a selection is given
+	artifacts := FilterArtifacts(all, opts.Selector)
 
 	// Pin mode: Deploy all targeted artifacts to specific commit
 	if opts.PinCommit != "" {
-		return createPinPlan(artifacts, cfg, lockFile, lockPath, opts.PinCommit), nil
+		plan := createPinPlan(artifacts, cfg, lockFile, lockPath, opts.PinCommit, opts.PinTarget)
+		plan.Artifacts = all
+		return plan, nil
+	}
+
+	// Promote mode: Deploy the commits recorded in another environment
+	if opts.PromoteFrom != "" {
+		sourceLock, err := config.LoadLock(config.LockFilePath(rootPath, opts.PromoteFrom))
+		if err != nil {
+			return nil, err
+		}
+		plan := createPromotePlan(artifacts, cfg, lockFile, lockPath, sourceLock, opts)
+		plan.Artifacts = all
+		return plan, nil
 	}
 
 	// Get current commit
@@ -82,113 +138,297 @@ func CreatePlanWithOptions(rootPath string, cfg *config.Config, opts PlanOptions
 
 	// Get uncommitted/untracked changes (same for all artifacts)
 	uncommittedFiles, _ := GetUncommittedChanges(rootPath)
+	uncommitted := newChangeIndex(uncommittedFiles)
 	plan := &Plan{
 		TotalChanges: len(uncommittedFiles),
 		LockFile:     lockFile,
 		LockPath:     lockPath,
+		Artifacts:    all,
 	}
 
-	for _, artifact := range artifacts {
-		relPath, _ := filepath.Rel(rootPath, artifact.Path)
+	// Tree mode: compare content hashes instead of diffing commits. Lock
+	// entries without a tree hash still use the diff.
+	var treeHashes map[string]string
+	if cfg.ChangeDetection == config.ChangeDetectionTree {
+		objects, err := getTreeObjects(rootPath)
+		if err != nil {
+			Warn("tree hashes unavailable, falling back to diff detection", "error", err)
+		} else {
+			treeHashes = make(map[string]string)
+			for _, artifact := range artifacts {
+				relPath, _ := filepath.Rel(rootPath, artifact.Path)
+				treeHashes[artifact.Artifact.Name] = treeHash(newChangeRules(relPath, artifact.Artifact), objects)
+			}
+		}
+	}
+	comparesTrees := func(key string) bool {
+		return treeHashes != nil && lockFile.GetTreeHash(key) != ""
+	}
 
-		// Check if artifact is pinned (e.g. after rollback)
-		// --force ignores pins
-		if !opts.Force && lockFile.IsPinned(artifact.Artifact.Name) {
-			plan.Actions = append(plan.Actions, PlannedAction{
-				Artifact: artifact,
-				Action:   ActionSkip,
-				Reason:   "pinned (use --force to override)",
-			})
-			plan.ToSkip++
-			continue
+	// Artifacts usually share their last deployed commit, so every distinct
+	// commit is diffed against HEAD only once
+	diffs := make(map[string]*commitDiff)
+	for _, artifact := range artifacts {
+		for _, unit := range deployUnits(artifact.Artifact) {
+			if !opts.Force && lockFile.IsPinned(unit.key) {
+				continue
+			}
+			if comparesTrees(unit.key) {
+				continue
+			}
+			lastDeployed := lockFile.GetLastDeployedCommit(unit.key)
+			if lastDeployed == "" || lastDeployed == currentCommit || diffs[lastDeployed] != nil {
+				continue
+			}
+			diffs[lastDeployed] = newCommitDiff(rootPath, lastDeployed)
 		}
+	}
+
+	for _, artifact := range artifacts {
+		relPath, _ := filepath.Rel(rootPath, artifact.Path)
+		rules := newChangeRules(relPath, artifact.Artifact)
 
 		// 1. Check uncommitted changes
-		affected, files := isArtifactAffected(relPath, uncommittedFiles)
-
-		// 2. Check changes since last deployment
-		lastDeployed := lockFile.GetLastDeployedCommit(artifact.Artifact.Name)
-		if lastDeployed != "" && lastDeployed != currentCommit {
-			// Get changes between lastDeployed and current HEAD
-			commitChanges, err := GetChangedFilesBetweenCommits(rootPath, lastDeployed, "HEAD")
-			if err != nil {
-				// Commit not found (e.g. fictitious commit in lock file) - mark as changed
-				affected = true
-				files = append(files, relPath+" (deployed commit not found)")
-			} else {
-				commitAffected, commitFiles := isArtifactAffected(relPath, commitChanges)
-				if commitAffected {
-					affected = true
-					files = append(files, commitFiles...)
-					plan.TotalChanges += len(commitChanges)
+		uncommittedAffected, uncommittedFiles := uncommitted.affected(rules)
+
+		// changes returns the files changed since the deployment recorded
+		// under a lock key, and whether they are unknown
+		changes := func(key string) (bool, []config.AffectedFile, bool) {
+			affected := uncommittedAffected
+			files := append([]config.AffectedFile(nil), uncommittedFiles...)
+			unknown := false
+
+			// 2. Check changes since last deployment
+			lastDeployed := lockFile.GetLastDeployedCommit(key)
+			if comparesTrees(key) {
+				if treeHashes[artifact.Artifact.Name] != lockFile.GetTreeHash(key) {
+					affected, unknown = true, true
+					files = append(files, config.AffectedFile{Path: rules.path, Reason: "tree hash changed"})
+				}
+			} else if diff := diffs[lastDeployed]; diff != nil {
+				if diff.err != nil {
+					// Commit not found (e.g. fictitious commit in lock file) - mark as changed
+					affected, unknown = true, true
+					files = append(files, config.AffectedFile{Path: rules.path, Reason: "deployed commit not found"})
+				} else {
+					commitAffected, commitFiles := diff.index.affected(rules)
+					if commitAffected {
+						affected = true
+						files = append(files, commitFiles...)
+						if !diff.counted {
+							diff.counted = true
+							plan.TotalChanges += len(diff.index.files)
+						}
+					}
 				}
+			} else if lastDeployed == "" {
+				// Never deployed - always mark as new artifact
+				affected, unknown = true, true
+				files = []config.AffectedFile{{Path: rules.path, Reason: "new artifact"}}
 			}
-		} else if lastDeployed == "" {
-			// Never deployed - always mark as new artifact
-			affected = true
-			files = []string{relPath + " (new artifact)"}
+			return affected, files, unknown
 		}
 
-		if affected {
-			// Find the validation steps for the language
-			validationSteps := getValidationSteps(cfg, artifact.Language)
+		// The artifact is validated once if any of its targets changed and
+		// deployed to each changed target
+		var validate *PlannedAction
+		var deploys, skips []PlannedAction
+		for _, unit := range deployUnits(artifact.Artifact) {
+			// Check if the target is pinned (e.g. after rollback)
+			// --force ignores pins
+			if !opts.Force && lockFile.IsPinned(unit.key) {
+				skips = append(skips, PlannedAction{
+					Artifact: artifact,
+					Action:   ActionSkip,
+					Target:   unit.target,
+					Reason:   "pinned (use --force to override)",
+				})
+				continue
+			}
 
-			// Find deploy steps from target (only for non-libraries)
-			var deploySteps []config.Step
-			if !artifact.Artifact.IsLib {
-				if t, ok := cfg.Targets[artifact.Artifact.Target]; ok {
-					deploySteps = t.Steps
-				}
+			affected, files, unknown := changes(unit.key)
+			if !affected {
+				skips = append(skips, PlannedAction{
+					Artifact: artifact,
+					Action:   ActionSkip,
+					Target:   unit.target,
+					Reason:   "no changes detected",
+				})
+				continue
 			}
 
-			plan.Actions = append(plan.Actions, PlannedAction{
-				Artifact:     artifact,
-				Action:       ActionValidate,
-				Reason:       "files changed",
-				Steps:        validationSteps,
-				ChangedFiles: files,
-			})
-			plan.ToValidate++
+			if validate == nil {
+				validate = &PlannedAction{
+					Artifact:       artifact,
+					Action:         ActionValidate,
+					Target:         artifact.Artifact.Target,
+					Reason:         "files changed",
+					Steps:          getValidationSteps(cfg, artifact.Language),
+					ChangedFiles:   files,
+					ChangesUnknown: unknown,
+				}
+			} else {
+				validate.ChangedFiles = mergeFiles(validate.ChangedFiles, files)
+				validate.ChangesUnknown = validate.ChangesUnknown || unknown
+			}
 
-			// If it's a deployable artifact (not a library), add deploy action
-			if !artifact.Artifact.IsLib && len(deploySteps) > 0 {
-				plan.Actions = append(plan.Actions, PlannedAction{
-					Artifact:     artifact,
-					Action:       ActionDeploy,
-					Reason:       "artifact changed",
-					Steps:        deploySteps,
-					ChangedFiles: files,
+			// Libraries have no target and are only validated
+			if steps := deploySteps(cfg, unit.target); unit.target != "" && len(steps) > 0 {
+				deploys = append(deploys, PlannedAction{
+					Artifact:       artifact,
+					Action:         ActionDeploy,
+					Target:         unit.target,
+					Reason:         "artifact changed",
+					Steps:          steps,
+					ChangedFiles:   files,
+					ChangesUnknown: unknown,
 				})
-				plan.ToDeploy++
 			}
-		} else {
-			plan.Actions = append(plan.Actions, PlannedAction{
-				Artifact: artifact,
-				Action:   ActionSkip,
-				Reason:   "no changes detected",
-			})
-			plan.ToSkip++
 		}
+
+		if validate != nil {
+			plan.Actions = append(plan.Actions, *validate)
+			plan.ToValidate++
+		}
+		plan.Actions = append(plan.Actions, deploys...)
+		plan.ToDeploy += len(deploys)
+		plan.Actions = append(plan.Actions, skips...)
+		plan.ToSkip += len(skips)
 	}
 
 	// Add dependent artifacts
 	plan.addDependentArtifacts(artifacts, cfg)
 
+	// Apply records the tree hashes in the lock file
+	if treeHashes != nil {
+		for i := range plan.Actions {
+			plan.Actions[i].TreeHash = treeHashes[plan.Actions[i].Artifact.Artifact.Name]
+		}
+	}
+
 	return plan, nil
 }
 
-func isArtifactAffected(artifactPath string, changedFiles []ChangedFile) (bool, []string) {
-	var affected []string
+// mergeFiles appends the files that are not in files yet
+func mergeFiles(files, more []config.AffectedFile) []config.AffectedFile {
+	seen := make(map[config.AffectedFile]bool, len(files))
+	for _, f := range files {
+		seen[f] = true
+	}
+	for _, f := range more {
+		if !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// commitDiff holds the changes between a deployed commit and HEAD
+type commitDiff struct {
+	index   *changeIndex
+	err     error
+	counted bool // Whether the changes were added to Plan.TotalChanges
+}
+
+// newCommitDiff diffs commit against HEAD
+func newCommitDiff(rootPath, commit string) *commitDiff {
+	changes, err := GetChangedFilesBetweenCommits(rootPath, commit, "HEAD")
+	if err != nil {
+		return &commitDiff{err: err}
+	}
+	return &commitDiff{index: newChangeIndex(changes)}
+}
+
+// changeRules describes which changed files belong to an artifact
+type changeRules struct {
+	path   string   // Artifact directory, relative to the workspace root
+	watch  []string // Extra paths or globs, relative to the artifact directory
+	ignore []string // Globs of ignored files, relative to the artifact directory
+}
+
+// newChangeRules builds the change rules for an artifact located at relPath
+func newChangeRules(relPath string, artifact *config.Artifact) changeRules {
+	return changeRules{
+		path:   filepath.ToSlash(relPath),
+		watch:  artifact.Watch,
+		ignore: artifact.Ignore,
+	}
+}
+
+// match returns the rule that attributes file to the artifact.
+// The rule is "" if the file does not belong to the artifact, "path" for
+// files inside the artifact directory and "watch: <entry>" for watched paths.
+func (r changeRules) match(file string) string {
+	rule := ""
+	if strings.HasPrefix(file, r.path+"/") || file == r.path {
+		rule = "path"
+	} else {
+		for _, w := range r.watch {
+			if matchPathOrParent(path.Join(r.path, w), file) {
+				rule = "watch: " + w
+				break
+			}
+		}
+	}
+	if rule == "" {
+		return ""
+	}
+
+	// Ignore patterns are matched relative to the artifact directory
+	rel := file
+	if r.path != "." {
+		rel = relSlash(r.path, file)
+	}
+	for _, pattern := range r.ignore {
+		if matchPathOrParent(pattern, rel) {
+			return ""
+		}
+	}
+
+	return rule
+}
+
+// relSlash returns target relative to base, both slash-separated
+func relSlash(base, target string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(base), filepath.FromSlash(target))
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
+
+// isArtifactAffected returns the changed files attributed to an artifact.
+// Files matched through a watch entry carry that rule as reason. Renames
+// and copies affect the artifacts of both their old and their new path.
+func isArtifactAffected(rules changeRules, changedFiles []ChangedFile) (bool, []config.AffectedFile) {
+	var affected []config.AffectedFile
 
 	for _, f := range changedFiles {
-		if strings.HasPrefix(f.Path, artifactPath+"/") || f.Path == artifactPath {
-			affected = append(affected, f.Path)
+		if rule := rules.match(f.Path); rule != "" {
+			affected = append(affected, affectedFile(f.Path, rule, ""))
+		} else if f.OldPath != "" {
+			if rule := rules.match(f.OldPath); rule != "" {
+				affected = append(affected, affectedFile(f.OldPath, rule, "moved to "+f.Path))
+			}
 		}
 	}
 
 	return len(affected) > 0, affected
 }
 
+// affectedFile describes a changed file with the rule that matched it
+func affectedFile(file, rule, note string) config.AffectedFile {
+	var reasons []string
+	if rule != "path" {
+		reasons = append(reasons, rule)
+	}
+	if note != "" {
+		reasons = append(reasons, note)
+	}
+	return config.AffectedFile{Path: file, Reason: strings.Join(reasons, ", ")}
+}
+
 func (p *Plan) addDependentArtifacts(artifacts []DiscoveredArtifact, cfg *config.Config) {
 	// Collect names of changed artifacts (validated or deployed)
 	changedNames := make(map[string]bool)
@@ -203,55 +443,127 @@ func (p *Plan) addDependentArtifacts(artifacts []DiscoveredArtifact, cfg *config
 	for changed {
 		changed = false
 		for i, action := range p.Actions {
-			if action.Action == ActionSkip {
-				for _, dep := range action.Artifact.Artifact.Depends {
-					if changedNames[dep] {
-						// Find validation steps for the language
-						validationSteps := getValidationSteps(cfg, action.Artifact.Language)
-
-						p.Actions[i].Action = ActionValidate
-						p.Actions[i].Reason = "dependency '" + dep + "' changed"
-						p.Actions[i].Steps = validationSteps
-						p.ToSkip--
-						p.ToValidate++
-
-						// Add deploy action (only for non-libraries)
-						if !action.Artifact.Artifact.IsLib {
-							if t, ok := cfg.Targets[action.Artifact.Artifact.Target]; ok {
-								p.Actions = append(p.Actions, PlannedAction{
-									Artifact: action.Artifact,
-									Action:   ActionDeploy,
-									Reason:   "dependency '" + dep + "' changed",
-									Steps:    t.Steps,
-								})
-								p.ToDeploy++
-							}
+			if action.Action != ActionSkip {
+				continue
+			}
+			for _, dep := range action.Artifact.Artifact.Depends {
+				if !changedNames[dep] {
+					continue
+				}
+				reason := "dependency '" + dep + "' changed"
+				name := action.Artifact.Artifact.Name
+
+				// Other targets of the artifact may already be validated,
+				// then only the deploy to this target is added. The files
+				// changed in the dependency are unknown to the artifact.
+				if changedNames[name] {
+					p.markChangesUnknown(name)
+					if steps := deploySteps(cfg, action.Target); action.Target != "" && len(steps) > 0 {
+						p.Actions[i] = PlannedAction{
+							Artifact:       action.Artifact,
+							Action:         ActionDeploy,
+							Target:         action.Target,
+							Reason:         reason,
+							Steps:          steps,
+							ChangesUnknown: true,
 						}
-
-						changedNames[action.Artifact.Artifact.Name] = true
+						p.ToSkip--
+						p.ToDeploy++
 						changed = true
-						break
 					}
+					break
+				}
+
+				// Find validation steps for the language
+				validationSteps := getValidationSteps(cfg, action.Artifact.Language)
+
+				p.Actions[i].Action = ActionValidate
+				p.Actions[i].Target = action.Artifact.Artifact.Target
+				p.Actions[i].Reason = reason
+				p.Actions[i].Steps = validationSteps
+				p.Actions[i].ChangesUnknown = true
+				p.ToSkip--
+				p.ToValidate++
+
+				// Add deploy action (only for non-libraries)
+				if steps := deploySteps(cfg, action.Target); action.Target != "" && len(steps) > 0 {
+					p.Actions = append(p.Actions, PlannedAction{
+						Artifact:       action.Artifact,
+						Action:         ActionDeploy,
+						Target:         action.Target,
+						Reason:         reason,
+						Steps:          steps,
+						ChangesUnknown: true,
+					})
+					p.ToDeploy++
 				}
+
+				changedNames[name] = true
+				changed = true
+				break
 			}
 		}
 	}
 }
 
-// filterArtifacts filters artifacts by the specified names
-func filterArtifacts(artifacts []DiscoveredArtifact, names []string) []DiscoveredArtifact {
-	if len(names) == 0 {
+// markChangesUnknown marks the validation and deploys of an artifact as
+// having unknown changed files
+func (p *Plan) markChangesUnknown(name string) {
+	for i, action := range p.Actions {
+		if action.Action != ActionSkip && action.Artifact.Artifact.Name == name {
+			p.Actions[i].ChangesUnknown = true
+		}
+	}
+}
+
+// FilterArtifacts returns the artifacts chosen by the selector, in their
+// original order. Dependencies are added before exclusions are applied, so
+// excluded tags always win.
+func FilterArtifacts(artifacts []DiscoveredArtifact, sel Selector) []DiscoveredArtifact {
+	if sel.IsEmpty() {
 		return artifacts
 	}
 
 	nameMap := make(map[string]bool)
-	for _, n := range names {
+	for _, n := range sel.Names {
 		nameMap[n] = true
 	}
 
+	selected := make(map[string]bool)
+	for _, a := range artifacts {
+		switch {
+		case len(sel.Names) == 0 && len(sel.Tags) == 0:
+			selected[a.Artifact.Name] = true // Only exclusions given
+		case nameMap[a.Artifact.Name] || matchTags(a.Artifact.Tags, sel.Tags):
+			selected[a.Artifact.Name] = true
+		}
+	}
+
+	// Add transitive dependencies
+	if sel.WithDeps {
+		byName := make(map[string]DiscoveredArtifact)
+		for _, a := range artifacts {
+			byName[a.Artifact.Name] = a
+		}
+		var queue []string
+		for name := range selected {
+			queue = append(queue, name)
+		}
+		for len(queue) > 0 {
+			name := queue[0]
+			queue = queue[1:]
+			for _, dep := range byName[name].Artifact.Depends {
+				if _, ok := byName[dep]; ok && !selected[dep] {
+					selected[dep] = true
+					queue = append(queue, dep)
+				}
+			}
+		}
+	}
+
 	var filtered []DiscoveredArtifact
 	for _, a := range artifacts {
-		if nameMap[a.Artifact.Name] {
+		if selected[a.Artifact.Name] && !matchTags(a.Artifact.Tags, sel.ExcludeTags) {
 			filtered = append(filtered, a)
 		}
 	}
@@ -259,8 +571,9 @@ func filterArtifacts(artifacts []DiscoveredArtifact, names []string) []Discovere
 	return filtered
 }
 
-// createPinPlan creates a plan for pinning artifacts to a specific commit
-func createPinPlan(artifacts []DiscoveredArtifact, cfg *config.Config, lockFile *config.LockFile, lockPath string, pinCommit string) *Plan {
+// createPinPlan creates a plan for pinning artifacts to a specific commit.
+// If pinTarget is set, only the deploys to that target are planned.
+func createPinPlan(artifacts []DiscoveredArtifact, cfg *config.Config, lockFile *config.LockFile, lockPath string, pinCommit, pinTarget string) *Plan {
 	plan := &Plan{
 		LockFile: lockFile,
 		LockPath: lockPath,
@@ -277,28 +590,30 @@ func createPinPlan(artifacts []DiscoveredArtifact, cfg *config.Config, lockFile
 
 		// Validation action
 		plan.Actions = append(plan.Actions, PlannedAction{
-			Artifact:  artifact,
-			Action:    ActionValidate,
-			Reason:    "pin to " + shortCommit,
-			Steps:     validationSteps,
-			PinCommit: pinCommit,
+			Artifact:       artifact,
+			Action:         ActionValidate,
+			Target:         artifact.Artifact.Target,
+			Reason:         "pin to " + shortCommit,
+			Steps:          validationSteps,
+			ChangesUnknown: true,
+			PinCommit:      pinCommit,
 		})
 		plan.ToValidate++
 
-		// Deploy action only for non-libraries
-		if !artifact.Artifact.IsLib {
-			var deploySteps []config.Step
-			if t, ok := cfg.Targets[artifact.Artifact.Target]; ok {
-				deploySteps = t.Steps
+		// Deploy actions only for non-libraries
+		for _, unit := range deployUnits(artifact.Artifact) {
+			if pinTarget != "" && unit.target != pinTarget {
+				continue
 			}
-
-			if len(deploySteps) > 0 {
+			if steps := deploySteps(cfg, unit.target); unit.target != "" && len(steps) > 0 {
 				plan.Actions = append(plan.Actions, PlannedAction{
-					Artifact:  artifact,
-					Action:    ActionDeploy,
-					Reason:    "pin to " + shortCommit,
-					Steps:     deploySteps,
-					PinCommit: pinCommit,
+					Artifact:       artifact,
+					Action:         ActionDeploy,
+					Target:         unit.target,
+					Reason:         "pin to " + shortCommit,
+					Steps:          steps,
+					ChangesUnknown: true,
+					PinCommit:      pinCommit,
 				})
 				plan.ToDeploy++
 			}
@@ -307,3 +622,67 @@ func createPinPlan(artifacts []DiscoveredArtifact, cfg *config.Config, lockFile
 
 	return plan
 }
+
+// createPromotePlan creates a plan that deploys the commits recorded in the
+// source environment's lock file. Validation already ran for these commits,
+// so only deploy actions are planned.
+func createPromotePlan(artifacts []DiscoveredArtifact, cfg *config.Config, lockFile *config.LockFile, lockPath string, sourceLock *config.LockFile, opts PlanOptions) *Plan {
+	plan := &Plan{
+		LockFile: lockFile,
+		LockPath: lockPath,
+	}
+
+	for _, artifact := range artifacts {
+		if artifact.Artifact.IsLib {
+			continue
+		}
+
+		// Each target is promoted to the commit deployed there
+		for _, unit := range deployUnits(artifact.Artifact) {
+			skip := func(reason string) {
+				plan.Actions = append(plan.Actions, PlannedAction{
+					Artifact: artifact,
+					Action:   ActionSkip,
+					Target:   unit.target,
+					Reason:   reason,
+				})
+				plan.ToSkip++
+			}
+
+			commit := sourceLock.GetLastDeployedCommit(unit.key)
+			if commit == "" {
+				skip("not deployed to " + opts.PromoteFrom)
+				continue
+			}
+			shortCommit := commit[:min(8, len(commit))]
+
+			if !opts.Force && lockFile.IsPinned(unit.key) {
+				skip("pinned (use --force to override)")
+				continue
+			}
+			if lockFile.GetLastDeployedCommit(unit.key) == commit {
+				skip("already at " + shortCommit)
+				continue
+			}
+
+			steps := deploySteps(cfg, unit.target)
+			if len(steps) == 0 {
+				skip("target has no deploy steps")
+				continue
+			}
+
+			plan.Actions = append(plan.Actions, PlannedAction{
+				Artifact:       artifact,
+				Action:         ActionDeploy,
+				Target:         unit.target,
+				Reason:         "promote " + shortCommit + " from " + opts.PromoteFrom,
+				Steps:          steps,
+				ChangesUnknown: true,
+				Commit:         commit,
+			})
+			plan.ToDeploy++
+		}
+	}
+
+	return plan
+}
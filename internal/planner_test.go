@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/irevolve/bear/internal/config"
@@ -112,7 +113,7 @@ func TestFilterArtifacts(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := filterArtifacts(artifacts, tt.names)
+			result := FilterArtifacts(artifacts, Selector{Names: tt.names})
 
 			if len(result) != tt.expectedCount {
 				t.Errorf("expected %d artifacts, got %d", tt.expectedCount, len(result))
@@ -127,6 +128,86 @@ func TestFilterArtifacts(t *testing.T) {
 	}
 }
 
+func TestFilterArtifacts_Tags(t *testing.T) {
+	artifacts := []DiscoveredArtifact{
+		{Artifact: &config.Artifact{Name: "payments-api", Tags: []string{"team-payments", "api"}, Depends: []string{"payments-lib"}}},
+		{Artifact: &config.Artifact{Name: "payments-worker", Tags: []string{"team-payments", "experimental"}}},
+		{Artifact: &config.Artifact{Name: "payments-lib", Depends: []string{"common"}, IsLib: true}},
+		{Artifact: &config.Artifact{Name: "common", Tags: []string{"experimental"}, IsLib: true}},
+		{Artifact: &config.Artifact{Name: "user-api", Tags: []string{"team-users", "api"}}},
+	}
+
+	tests := []struct {
+		name     string
+		selector Selector
+		expected []string
+	}{
+		{
+			name:     "tag",
+			selector: Selector{Tags: []string{"team-payments"}},
+			expected: []string{"payments-api", "payments-worker"},
+		},
+		{
+			name:     "any of several tags",
+			selector: Selector{Tags: []string{"team-users", "experimental"}},
+			expected: []string{"payments-worker", "common", "user-api"},
+		},
+		{
+			name:     "all of joined tags",
+			selector: Selector{Tags: []string{"team-payments+api"}},
+			expected: []string{"payments-api"},
+		},
+		{
+			name:     "exclude tag",
+			selector: Selector{Tags: []string{"team-payments"}, ExcludeTags: []string{"experimental"}},
+			expected: []string{"payments-api"},
+		},
+		{
+			name:     "only exclude tag",
+			selector: Selector{ExcludeTags: []string{"experimental"}},
+			expected: []string{"payments-api", "payments-lib", "user-api"},
+		},
+		{
+			name:     "names and tags",
+			selector: Selector{Names: []string{"user-api"}, Tags: []string{"experimental"}},
+			expected: []string{"payments-worker", "common", "user-api"},
+		},
+		{
+			name:     "with dependencies",
+			selector: Selector{Tags: []string{"api"}, WithDeps: true},
+			expected: []string{"payments-api", "payments-lib", "common", "user-api"},
+		},
+		{
+			name:     "exclusion wins over dependencies",
+			selector: Selector{Names: []string{"payments-api"}, ExcludeTags: []string{"experimental"}, WithDeps: true},
+			expected: []string{"payments-api", "payments-lib"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, a := range FilterArtifacts(artifacts, tt.selector) {
+				got = append(got, a.Artifact.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSelector_Validate(t *testing.T) {
+	if err := (Selector{Tags: []string{"a+b"}, ExcludeTags: []string{"c"}}).Validate(); err != nil {
+		t.Errorf("expected valid selector, got %v", err)
+	}
+	for _, expr := range []string{"", "a+", "+b", "a++b"} {
+		if err := (Selector{Tags: []string{expr}}).Validate(); err == nil {
+			t.Errorf("expected error for tag selector '%s'", expr)
+		}
+	}
+}
+
 func TestIsArtifactAffected(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -180,11 +261,38 @@ func TestIsArtifactAffected(t *testing.T) {
 			expectHit:   true,
 			expectCount: 2,
 		},
+		{
+			name:         "file moved out of artifact",
+			artifactPath: "services/api",
+			changedFiles: []ChangedFile{
+				{Status: "R100", Path: "libs/common/util.go", OldPath: "services/api/util.go"},
+			},
+			expectHit:   true,
+			expectCount: 1,
+		},
+		{
+			name:         "file moved into artifact",
+			artifactPath: "services/api",
+			changedFiles: []ChangedFile{
+				{Status: "R100", Path: "services/api/util.go", OldPath: "libs/common/util.go"},
+			},
+			expectHit:   true,
+			expectCount: 1,
+		},
+		{
+			name:         "rename between other artifacts",
+			artifactPath: "services/api",
+			changedFiles: []ChangedFile{
+				{Status: "R100", Path: "services/web/util.go", OldPath: "libs/common/util.go"},
+			},
+			expectHit:   false,
+			expectCount: 0,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			affected, files := isArtifactAffected(tt.artifactPath, tt.changedFiles)
+			affected, files := isArtifactAffected(changeRules{path: tt.artifactPath}, tt.changedFiles)
 
 			if affected != tt.expectHit {
 				t.Errorf("expected affected=%v, got %v", tt.expectHit, affected)
@@ -196,3 +304,311 @@ func TestIsArtifactAffected(t *testing.T) {
 		})
 	}
 }
+
+func TestIsArtifactAffected_WatchAndIgnore(t *testing.T) {
+	rules := newChangeRules("services/api", &config.Artifact{
+		Name:   "api",
+		Watch:  []string{"../../proto", "../../schemas/*.json"},
+		Ignore: []string{"**/*.md", "**/*_test.go", "docs"},
+	})
+
+	tests := []struct {
+		name     string
+		file     string
+		expected []config.AffectedFile
+	}{
+		{
+			name:     "file in artifact directory",
+			file:     "services/api/main.go",
+			expected: []config.AffectedFile{{Path: "services/api/main.go"}},
+		},
+		{
+			name:     "ignored markdown",
+			file:     "services/api/README.md",
+			expected: nil,
+		},
+		{
+			name:     "ignored nested test file",
+			file:     "services/api/handlers/user_test.go",
+			expected: nil,
+		},
+		{
+			name:     "ignored directory",
+			file:     "services/api/docs/api.yaml",
+			expected: nil,
+		},
+		{
+			name:     "watched directory outside artifact",
+			file:     "proto/user/v1/user.proto",
+			expected: []config.AffectedFile{{Path: "proto/user/v1/user.proto", Reason: "watch: ../../proto"}},
+		},
+		{
+			name:     "watched glob outside artifact",
+			file:     "schemas/user.json",
+			expected: []config.AffectedFile{{Path: "schemas/user.json", Reason: "watch: ../../schemas/*.json"}},
+		},
+		{
+			name:     "watched glob does not match nested file",
+			file:     "schemas/v2/user.json",
+			expected: nil,
+		},
+		{
+			name:     "ignore applies to watched files",
+			file:     "proto/README.md",
+			expected: nil,
+		},
+		{
+			name:     "unrelated file",
+			file:     "services/web/main.go",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			affected, files := isArtifactAffected(rules, []ChangedFile{{Path: tt.file}})
+
+			if affected != (len(tt.expected) > 0) {
+				t.Errorf("expected affected=%v, got %v", len(tt.expected) > 0, affected)
+			}
+			if len(files) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, files)
+			}
+			for i := range files {
+				if files[i] != tt.expected[i] {
+					t.Errorf("expected '%s', got '%s'", tt.expected[i], files[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*.md", "README.md", true},
+		{"*.md", "docs/README.md", false},
+		{"**/*.md", "README.md", true},
+		{"**/*.md", "docs/guide/README.md", true},
+		{"docs/**", "docs/guide/README.md", true},
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/pkg/util.go", true},
+		{"src/**/*.go", "lib/pkg/util.go", false},
+		{"../proto/*.proto", "../proto/user.proto", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.name); got != tt.match {
+			t.Errorf("MatchGlob(%q, %q) = %v, expected %v", tt.pattern, tt.name, got, tt.match)
+		}
+	}
+}
+
+func TestCreatePromotePlan(t *testing.T) {
+	cfg := &config.Config{
+		Targets: map[string]config.Target{
+			"cloudrun": {Steps: []config.Step{{Name: "Deploy", Run: "gcloud run deploy"}}},
+		},
+	}
+	artifacts := []DiscoveredArtifact{
+		{Artifact: &config.Artifact{Name: "user-api", Target: "cloudrun"}},
+		{Artifact: &config.Artifact{Name: "order-api", Target: "cloudrun"}},
+		{Artifact: &config.Artifact{Name: "billing", Target: "cloudrun"}},
+		{Artifact: &config.Artifact{Name: "pinned-api", Target: "cloudrun"}},
+		{Artifact: &config.Artifact{Name: "shared-lib", IsLib: true}},
+	}
+	source := &config.LockFile{Artifacts: map[string]config.LockEntry{
+		"user-api":   {Commit: "aaa111"},
+		"order-api":  {Commit: "bbb222"},
+		"pinned-api": {Commit: "ccc333"},
+	}}
+	target := &config.LockFile{Artifacts: map[string]config.LockEntry{
+		"order-api":  {Commit: "bbb222"},
+		"pinned-api": {Commit: "old000", Pinned: true},
+	}}
+
+	plan := createPromotePlan(artifacts, cfg, target, "bear.lock.prod.yml", source, PlanOptions{PromoteFrom: "staging"})
+
+	if plan.ToDeploy != 1 || plan.ToSkip != 3 {
+		t.Fatalf("expected 1 deploy and 3 skips, got %d and %d", plan.ToDeploy, plan.ToSkip)
+	}
+
+	reasons := make(map[string]string)
+	for _, action := range plan.Actions {
+		reasons[action.Artifact.Artifact.Name] = action.Reason
+		if action.Action == ActionDeploy && action.Commit != "aaa111" {
+			t.Errorf("expected promote commit 'aaa111', got '%s'", action.Commit)
+		}
+	}
+	if reasons["order-api"] != "already at bbb222" {
+		t.Errorf("unexpected reason for order-api: '%s'", reasons["order-api"])
+	}
+	if reasons["billing"] != "not deployed to staging" {
+		t.Errorf("unexpected reason for billing: '%s'", reasons["billing"])
+	}
+	if reasons["pinned-api"] != "pinned (use --force to override)" {
+		t.Errorf("unexpected reason for pinned-api: '%s'", reasons["pinned-api"])
+	}
+}
+
+func TestCreatePromotePlan_SeveralTargets(t *testing.T) {
+	cfg := &config.Config{
+		Targets: map[string]config.Target{
+			"service": {Steps: []config.Step{{Name: "Deploy", Run: "gcloud run deploy"}}},
+			"job":     {Steps: []config.Step{{Name: "Deploy", Run: "gcloud run jobs deploy"}}},
+		},
+	}
+	artifacts := []DiscoveredArtifact{
+		{Artifact: &config.Artifact{Name: "api", Targets: []config.ArtifactTarget{{Name: "service"}, {Name: "job"}}}},
+	}
+	source := &config.LockFile{Artifacts: map[string]config.LockEntry{
+		"api@service": {Commit: "aaa111"},
+		"api@job":     {Commit: "bbb222"},
+	}}
+	target := &config.LockFile{Artifacts: map[string]config.LockEntry{
+		"api@job": {Commit: "bbb222"},
+	}}
+
+	plan := createPromotePlan(artifacts, cfg, target, "bear.lock.prod.yml", source, PlanOptions{PromoteFrom: "staging"})
+
+	if plan.ToDeploy != 1 || plan.ToSkip != 1 {
+		t.Fatalf("expected 1 deploy and 1 skip, got %d and %d", plan.ToDeploy, plan.ToSkip)
+	}
+	for _, action := range plan.Actions {
+		switch action.Target {
+		case "service":
+			if action.Action != ActionDeploy || action.Commit != "aaa111" {
+				t.Errorf("expected deploy of 'aaa111' to service, got %s of '%s'", action.Action, action.Commit)
+			}
+		case "job":
+			if action.Action != ActionSkip || action.Reason != "already at bbb222" {
+				t.Errorf("expected job to be skipped, got %s (%s)", action.Action, action.Reason)
+			}
+		default:
+			t.Errorf("unexpected target '%s'", action.Target)
+		}
+	}
+}
+
+func TestAddDependentArtifacts_SeveralTargets(t *testing.T) {
+	cfg := &config.Config{
+		Targets: map[string]config.Target{
+			"service": {Steps: []config.Step{{Name: "Deploy", Run: "deploy service"}}},
+			"job":     {Steps: []config.Step{{Name: "Deploy", Run: "deploy job"}}},
+		},
+	}
+	lib := DiscoveredArtifact{Artifact: &config.Artifact{Name: "shared", IsLib: true}}
+	api := DiscoveredArtifact{Artifact: &config.Artifact{
+		Name:    "api",
+		Depends: []string{"shared"},
+		Targets: []config.ArtifactTarget{{Name: "service"}, {Name: "job"}},
+	}}
+
+	plan := &Plan{
+		Actions: []PlannedAction{
+			{Artifact: lib, Action: ActionValidate},
+			{Artifact: api, Action: ActionSkip, Target: "service"},
+			{Artifact: api, Action: ActionSkip, Target: "job"},
+		},
+		ToValidate: 1,
+		ToSkip:     2,
+	}
+	plan.addDependentArtifacts([]DiscoveredArtifact{lib, api}, cfg)
+
+	if plan.ToValidate != 2 || plan.ToDeploy != 2 || plan.ToSkip != 0 {
+		t.Fatalf("expected 2 validations and 2 deploys, got %d, %d and %d skips", plan.ToValidate, plan.ToDeploy, plan.ToSkip)
+	}
+	deployed := make(map[string]bool)
+	for _, action := range plan.Actions {
+		if action.Action == ActionDeploy {
+			deployed[action.Target] = true
+		}
+	}
+	if !deployed["service"] || !deployed["job"] {
+		t.Errorf("expected deploys to service and job, got %v", deployed)
+	}
+}
+
+func TestPlannedActions_ChangesUnknown(t *testing.T) {
+	cfg := &config.Config{
+		Targets: map[string]config.Target{
+			"cloudrun": {Steps: []config.Step{{Name: "Deploy", Run: "gcloud run deploy"}}},
+		},
+	}
+	lib := DiscoveredArtifact{Artifact: &config.Artifact{Name: "shared", IsLib: true}}
+	api := DiscoveredArtifact{Artifact: &config.Artifact{Name: "api", Target: "cloudrun", Depends: []string{"shared"}}}
+
+	dependent := &Plan{
+		Actions: []PlannedAction{
+			{Artifact: lib, Action: ActionValidate, ChangedFiles: []config.AffectedFile{{Path: "shared/util.go"}}},
+			{Artifact: api, Action: ActionSkip, Target: "cloudrun"},
+		},
+		ToValidate: 1,
+		ToSkip:     1,
+	}
+	dependent.addDependentArtifacts([]DiscoveredArtifact{lib, api}, cfg)
+
+	source := &config.LockFile{Artifacts: map[string]config.LockEntry{"api": {Commit: "aaa111"}}}
+	target := &config.LockFile{Artifacts: map[string]config.LockEntry{}}
+
+	tests := []struct {
+		name string
+		plan *Plan
+	}{
+		{"dependency changed", dependent},
+		{"pin", createPinPlan([]DiscoveredArtifact{api}, cfg, target, "bear.lock.yml", "abc123", "")},
+		{"promote", createPromotePlan([]DiscoveredArtifact{api}, cfg, target, "bear.lock.prod.yml", source, PlanOptions{PromoteFrom: "staging"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var actions int
+			for _, action := range tt.plan.Actions {
+				if action.Artifact.Artifact.Name != "api" || action.Action == ActionSkip {
+					continue
+				}
+				actions++
+				if !action.ChangesUnknown {
+					t.Errorf("expected unknown changes for %s of api", action.Action)
+				}
+			}
+			if actions == 0 {
+				t.Error("expected actions for api")
+			}
+		})
+	}
+
+	for _, action := range dependent.Actions {
+		if action.Artifact.Artifact.Name == "shared" && action.ChangesUnknown {
+			t.Error("expected known changes for shared")
+		}
+	}
+}
+
+func TestCreatePinPlan_PinTarget(t *testing.T) {
+	cfg := &config.Config{
+		Targets: map[string]config.Target{
+			"service": {Steps: []config.Step{{Name: "Deploy", Run: "deploy service"}}},
+			"job":     {Steps: []config.Step{{Name: "Deploy", Run: "deploy job"}}},
+		},
+	}
+	api := DiscoveredArtifact{Artifact: &config.Artifact{
+		Name:    "api",
+		Targets: []config.ArtifactTarget{{Name: "service"}, {Name: "job"}},
+	}}
+	lockFile := &config.LockFile{Artifacts: map[string]config.LockEntry{}}
+
+	plan := createPinPlan([]DiscoveredArtifact{api}, cfg, lockFile, "bear.lock.yml", "abc123", "job")
+
+	if plan.ToValidate != 1 || plan.ToDeploy != 1 {
+		t.Fatalf("expected 1 validation and 1 deploy, got %d and %d", plan.ToValidate, plan.ToDeploy)
+	}
+	for _, action := range plan.Actions {
+		if action.Action == ActionDeploy && action.Target != "job" {
+			t.Errorf("expected only a deploy to job, got '%s'", action.Target)
+		}
+	}
+}
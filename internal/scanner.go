@@ -3,6 +3,7 @@ package internal
 import (
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/irevolve/bear/internal/config"
 )
@@ -37,12 +38,10 @@ func ScanArtifacts(rootPath string, cfg *config.Config) ([]DiscoveredArtifact, e
 			}
 
 			dir := filepath.Dir(path)
-			lang := detectLanguage(dir, cfg.Languages)
-
 			artifacts = append(artifacts, DiscoveredArtifact{
 				Path:     dir,
 				Artifact: artifact,
-				Language: lang,
+				Language: artifactLanguage(dir, artifact, cfg.Languages),
 			})
 		} else if isLib {
 			lib, err := config.LoadLibrary(path)
@@ -51,12 +50,11 @@ func ScanArtifacts(rootPath string, cfg *config.Config) ([]DiscoveredArtifact, e
 			}
 
 			dir := filepath.Dir(path)
-			lang := detectLanguage(dir, cfg.Languages)
-
+			artifact := lib.ToArtifact()
 			artifacts = append(artifacts, DiscoveredArtifact{
 				Path:     dir,
-				Artifact: lib.ToArtifact(),
-				Language: lang,
+				Artifact: artifact,
+				Language: artifactLanguage(dir, artifact, cfg.Languages),
 			})
 		}
 
@@ -70,24 +68,60 @@ func ScanArtifacts(rootPath string, cfg *config.Config) ([]DiscoveredArtifact, e
 	return artifacts, nil
 }
 
-// detectLanguage detects the language of a directory based on detection rules
+// artifactLanguage returns the language set in the artifact or else the
+// detected language of its directory
+func artifactLanguage(dir string, artifact *config.Artifact, languages map[string]config.Language) string {
+	if artifact.Language != "" {
+		return artifact.Language
+	}
+	return detectLanguage(dir, languages)
+}
+
+// detectLanguage detects the language of a directory based on detection rules.
+// If several languages match, the one with the highest priority wins.
 func detectLanguage(dir string, languages map[string]config.Language) string {
+	if matches := MatchLanguages(dir, languages); len(matches) > 0 {
+		return matches[0]
+	}
+	return "unknown"
+}
+
+// MatchLanguages returns all languages whose detection rules match dir,
+// ordered by priority (highest first) and then by name
+func MatchLanguages(dir string, languages map[string]config.Language) []string {
+	var matched []string
 	for name, lang := range languages {
-		// Check if one of the detection files exists
-		for _, file := range lang.Detection.Files {
-			if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
-				return name
-			}
+		if matchesDetection(dir, lang.Detection) {
+			matched = append(matched, name)
 		}
+	}
 
-		// Check pattern
-		if lang.Detection.Pattern != "" {
-			matches, err := filepath.Glob(filepath.Join(dir, lang.Detection.Pattern))
-			if err == nil && len(matches) > 0 {
-				return name
-			}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := languages[matched[i]], languages[matched[j]]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return matched[i] < matched[j]
+	})
+	return matched
+}
+
+// matchesDetection checks the detection rules of a language against dir
+func matchesDetection(dir string, detection config.Detection) bool {
+	// Check if one of the detection files exists
+	for _, file := range detection.Files {
+		if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+			return true
 		}
 	}
 
-	return "unknown"
+	// Check pattern
+	if detection.Pattern != "" {
+		matches, err := filepath.Glob(filepath.Join(dir, detection.Pattern))
+		if err == nil && len(matches) > 0 {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+func TestMatchLanguages(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"package.json", "pyproject.toml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		languages map[string]config.Language
+		want      []string
+	}{
+		{
+			name: "same priority by name",
+			languages: map[string]config.Language{
+				"python": {Detection: config.Detection{Files: []string{"pyproject.toml"}}},
+				"node":   {Detection: config.Detection{Files: []string{"package.json"}}},
+				"go":     {Detection: config.Detection{Files: []string{"go.mod"}}},
+			},
+			want: []string{"node", "python"},
+		},
+		{
+			name: "higher priority first",
+			languages: map[string]config.Language{
+				"python": {Detection: config.Detection{Files: []string{"pyproject.toml"}}, Priority: 10},
+				"node":   {Detection: config.Detection{Pattern: "*.json"}},
+			},
+			want: []string{"python", "node"},
+		},
+		{
+			name: "no match",
+			languages: map[string]config.Language{
+				"go": {Detection: config.Detection{Files: []string{"go.mod"}}},
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Map order is random, repeat to catch nondeterminism
+			for i := 0; i < 10; i++ {
+				if got := MatchLanguages(dir, tt.languages); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestScanArtifacts_Language(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"api/bear.artifact.yml": "name: api\nlanguage: python\ntarget: cloudrun\n",
+		"api/package.json":      "{}",
+		"web/bear.artifact.yml": "name: web\ntarget: cloudrun\n",
+		"web/package.json":      "{}",
+		"lib/bear.lib.yml":      "name: lib\nlanguage: python\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	cfg := &config.Config{Languages: map[string]config.Language{
+		"node":   {Detection: config.Detection{Files: []string{"package.json"}}},
+		"python": {Detection: config.Detection{Files: []string{"pyproject.toml"}}},
+	}}
+
+	artifacts, err := ScanArtifacts(dir, cfg)
+	if err != nil {
+		t.Fatalf("ScanArtifacts failed: %v", err)
+	}
+
+	want := map[string]string{"api": "python", "web": "node", "lib": "python"}
+	for _, a := range artifacts {
+		if a.Language != want[a.Artifact.Name] {
+			t.Errorf("expected language '%s' for %s, got '%s'", want[a.Artifact.Name], a.Artifact.Name, a.Language)
+		}
+	}
+}
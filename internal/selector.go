@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Selector selects artifacts by name and tags. An artifact is selected if
+// it is named or matches one of the tags, and it is not excluded.
+type Selector struct {
+	Names       []string // Artifact names
+	Tags        []string // Tag expressions, any of them must match
+	ExcludeTags []string // Tag expressions of artifacts to leave out
+	WithDeps    bool     // Also select the dependencies of selected artifacts
+}
+
+// IsEmpty reports whether the selector selects all artifacts
+func (s Selector) IsEmpty() bool {
+	return len(s.Names) == 0 && len(s.Tags) == 0 && len(s.ExcludeTags) == 0
+}
+
+// Validate checks the tag expressions of the selector
+func (s Selector) Validate() error {
+	for _, expr := range append(append([]string(nil), s.Tags...), s.ExcludeTags...) {
+		for _, tag := range strings.Split(expr, "+") {
+			if strings.TrimSpace(tag) == "" {
+				return fmt.Errorf("invalid tag selector '%s' (expected tags joined with +, e.g. team-payments+api)", expr)
+			}
+		}
+	}
+	return nil
+}
+
+// String describes the selector for messages, e.g. "user-api, tag payments, not tag experimental"
+func (s Selector) String() string {
+	parts := append([]string(nil), s.Names...)
+	for _, expr := range s.Tags {
+		parts = append(parts, "tag "+expr)
+	}
+	for _, expr := range s.ExcludeTags {
+		parts = append(parts, "not tag "+expr)
+	}
+	if s.WithDeps {
+		parts = append(parts, "with dependencies")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// matchTags reports whether tags match any of the expressions. An
+// expression joins tags with "+" that must all be present.
+func matchTags(tags []string, exprs []string) bool {
+	has := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		has[tag] = true
+	}
+
+	for _, expr := range exprs {
+		matched := true
+		for _, tag := range strings.Split(expr, "+") {
+			if !has[strings.TrimSpace(tag)] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HTTPBackend stores the lock on a remote server.
+//
+// The server is expected to implement:
+//
+//	GET    <url>           200 with the LockInfo as JSON, 404 if unlocked
+//	PUT    <url>           Acquire with LockInfo body: 200, or 409 with the current LockInfo
+//	DELETE <url>?id=<id>   Release: 200, or 409 with the current LockInfo if the ID differs
+//	DELETE <url>           Force release: 200
+//
+// Servers should treat expired locks as unlocked.
+type HTTPBackend struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHTTPBackend creates a backend for the lock at url. If token is set,
+// it is sent as bearer token.
+func NewHTTPBackend(url, token string) *HTTPBackend {
+	return &HTTPBackend{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Lock acquires the lock
+func (b *HTTPBackend) Lock(info LockInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.do(http.MethodPut, b.url, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	case http.StatusConflict, http.StatusLocked:
+		return lockedErrorFrom(resp)
+	default:
+		return fmt.Errorf("failed to acquire state lock: HTTP %d", resp.StatusCode)
+	}
+}
+
+// Unlock releases the lock if it is held with the given ID
+func (b *HTTPBackend) Unlock(id string) error {
+	resp, err := b.do(http.MethodDelete, b.url+"?id="+url.QueryEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	case http.StatusConflict, http.StatusLocked:
+		return lockedErrorFrom(resp)
+	default:
+		return fmt.Errorf("failed to release state lock: HTTP %d", resp.StatusCode)
+	}
+}
+
+// Info returns the current lock, or nil if the state is not locked
+func (b *HTTPBackend) Info() (*LockInfo, error) {
+	resp, err := b.do(http.MethodGet, b.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var info LockInfo
+		if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+			return nil, fmt.Errorf("invalid state lock response: %w", err)
+		}
+		return &info, nil
+	case http.StatusNotFound, http.StatusNoContent:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("failed to read state lock: HTTP %d", resp.StatusCode)
+	}
+}
+
+// ForceUnlock removes the lock regardless of its holder
+func (b *HTTPBackend) ForceUnlock() error {
+	resp, err := b.do(http.MethodDelete, b.url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("failed to force-unlock state: HTTP %d", resp.StatusCode)
+	}
+}
+
+// do sends a request to the lock server
+func (b *HTTPBackend) do(method, url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+	req.Header.Set("User-Agent", "Bear-CI/1.0")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("state lock request to %s failed: %w", url, err)
+	}
+	return resp, nil
+}
+
+// lockedErrorFrom builds a LockedError from a conflict response
+func lockedErrorFrom(resp *http.Response) error {
+	data, _ := io.ReadAll(resp.Body)
+
+	var info LockInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return fmt.Errorf("state is locked by someone else (HTTP %d)", resp.StatusCode)
+	}
+	return &LockedError{Info: info}
+}
@@ -0,0 +1,153 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LocalBackend stores the lock in a file. Creating the file is atomic, so
+// only one process can hold the lock at a time; see takeOver for expired locks.
+type LocalBackend struct {
+	path string
+}
+
+// NewLocalBackend creates a backend that stores the lock at path
+func NewLocalBackend(path string) *LocalBackend {
+	return &LocalBackend{path: path}
+}
+
+// Lock acquires the lock, taking over an expired lock
+func (b *LocalBackend) Lock(info LockInfo) error {
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = b.create(data)
+	if !errors.Is(err, os.ErrExist) {
+		return err
+	}
+
+	current, err := b.Info()
+	if err != nil {
+		return err
+	}
+	if current != nil && !current.Expired() {
+		return &LockedError{Info: *current}
+	}
+	return b.takeOver(data)
+}
+
+// create writes the lock file if it does not exist yet
+func (b *LocalBackend) create(data []byte) error {
+	f, err := os.OpenFile(b.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// takeOver replaces an expired lock. Only the process that creates the
+// takeover marker may replace it, and it checks the lock again while holding
+// the marker, so a lock taken over by a faster process is not replaced. A
+// marker left behind by a killed process is removed by ForceUnlock.
+func (b *LocalBackend) takeOver(data []byte) error {
+	marker, err := os.OpenFile(b.markerPath(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("expired state lock %s is being taken over by another run", b.path)
+	}
+	if err != nil {
+		return err
+	}
+	marker.Close()
+	defer os.Remove(b.markerPath())
+
+	current, err := b.Info()
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		err := b.create(data)
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("failed to acquire state lock %s", b.path)
+		}
+		return err
+	}
+	if !current.Expired() {
+		return &LockedError{Info: *current}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), filepath.Base(b.path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), b.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// markerPath returns the path of the takeover marker
+func (b *LocalBackend) markerPath() string {
+	return b.path + ".takeover"
+}
+
+// Unlock releases the lock if it is held with the given ID
+func (b *LocalBackend) Unlock(id string) error {
+	current, err := b.Info()
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return nil
+	}
+	if current.ID != id {
+		return &LockedError{Info: *current}
+	}
+	return b.ForceUnlock()
+}
+
+// Info returns the current lock, or nil if the state is not locked
+func (b *LocalBackend) Info() (*LockInfo, error) {
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var info LockInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("invalid state lock %s: %w", b.path, err)
+	}
+	return &info, nil
+}
+
+// ForceUnlock removes the lock file and a takeover marker left behind
+func (b *LocalBackend) ForceUnlock() error {
+	for _, path := range []string{b.path, b.markerPath()} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
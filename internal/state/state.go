@@ -0,0 +1,144 @@
+// Package state provides an advisory lock around the deployment state, so
+// that concurrent plan and apply runs cannot overwrite each other's lock file.
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+// DefaultTTL is the lock lifetime when none is configured
+const DefaultTTL = time.Hour
+
+// LockInfo describes the holder of a state lock
+type LockInfo struct {
+	ID          string    `json:"id"`
+	Holder      string    `json:"holder"`    // user@host
+	Operation   string    `json:"operation"` // e.g. "plan" or "apply"
+	Environment string    `json:"environment,omitempty"`
+	Created     time.Time `json:"created"`
+	Expires     time.Time `json:"expires"`
+}
+
+// Expired reports whether the lock can be taken over by someone else
+func (i LockInfo) Expired() bool {
+	return !i.Expires.IsZero() && time.Now().After(i.Expires)
+}
+
+// String returns a human readable description of the lock holder
+func (i LockInfo) String() string {
+	return fmt.Sprintf("%s (%s, since %s, expires %s, id %s)",
+		i.Holder, i.Operation,
+		i.Created.UTC().Format(time.RFC3339),
+		i.Expires.UTC().Format(time.RFC3339),
+		i.ID)
+}
+
+// NewLockInfo creates lock info for the current process
+func NewLockInfo(operation, env string, ttl time.Duration) LockInfo {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	holder := "unknown"
+	if u, err := user.Current(); err == nil {
+		holder = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		holder += "@" + host
+	}
+
+	now := time.Now().UTC()
+	return LockInfo{
+		ID:          newID(),
+		Holder:      holder,
+		Operation:   operation,
+		Environment: env,
+		Created:     now,
+		Expires:     now.Add(ttl),
+	}
+}
+
+// newID returns a random lock ID
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// LockedError is returned when the state is locked by someone else
+type LockedError struct {
+	Info LockInfo
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("state is locked by %s", e.Info)
+}
+
+// Backend stores the advisory state lock
+type Backend interface {
+	// Lock acquires the lock. It returns a *LockedError if an unexpired
+	// lock is held by someone else.
+	Lock(info LockInfo) error
+	// Unlock releases the lock if it is held with the given ID.
+	Unlock(id string) error
+	// Info returns the current lock, or nil if the state is not locked.
+	Info() (*LockInfo, error)
+	// ForceUnlock removes the lock regardless of its holder.
+	ForceUnlock() error
+}
+
+// New creates the backend configured in bear.config.yml for an environment.
+// It returns nil if state locking is disabled.
+func New(cfg config.StateLock, rootPath, project, env string) (Backend, error) {
+	key := env
+	if key == "" {
+		key = "default"
+	}
+
+	switch cfg.Backend {
+	case "", "local":
+		name := "state.lock"
+		if env != "" {
+			name = "state." + env + ".lock"
+		}
+		return NewLocalBackend(filepath.Join(config.BearDir(rootPath), name)), nil
+	case "http":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("state_lock: url is required for the http backend")
+		}
+		token := ""
+		if cfg.TokenEnv != "" {
+			token = os.Getenv(cfg.TokenEnv)
+		}
+		lockURL := strings.TrimRight(cfg.URL, "/") + "/" + url.PathEscape(project) + "/" + url.PathEscape(key)
+		return NewHTTPBackend(lockURL, token), nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("state_lock: unknown backend '%s' (use local, http or none)", cfg.Backend)
+	}
+}
+
+// ParseTTL parses the configured lock lifetime, falling back to DefaultTTL
+func ParseTTL(cfg config.StateLock) (time.Duration, error) {
+	if cfg.TTL == "" {
+		return DefaultTTL, nil
+	}
+	ttl, err := time.ParseDuration(cfg.TTL)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("state_lock: invalid ttl '%s'", cfg.TTL)
+	}
+	return ttl, nil
+}
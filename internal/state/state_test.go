@@ -0,0 +1,254 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+// lockServer is an in-memory implementation of the HTTP lock protocol
+type lockServer struct {
+	mu    sync.Mutex
+	locks map[string]LockInfo
+}
+
+func (s *lockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, held := s.locks[r.URL.Path]
+	if held && current.Expired() {
+		held = false
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if !held {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(current)
+	case http.MethodPut:
+		if held {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(current)
+			return
+		}
+		var info LockInfo
+		if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.locks[r.URL.Path] = info
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if held && id != "" && id != current.ID {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(current)
+			return
+		}
+		delete(s.locks, r.URL.Path)
+	}
+}
+
+func testBackends(t *testing.T) map[string]Backend {
+	server := httptest.NewServer(&lockServer{locks: make(map[string]LockInfo)})
+	t.Cleanup(server.Close)
+
+	return map[string]Backend{
+		"local": NewLocalBackend(filepath.Join(t.TempDir(), "state.lock")),
+		"http":  NewHTTPBackend(server.URL+"/project/default", ""),
+	}
+}
+
+func TestBackend_LockAndUnlock(t *testing.T) {
+	for name, backend := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			first := NewLockInfo("apply", "", time.Hour)
+			if err := backend.Lock(first); err != nil {
+				t.Fatalf("Lock failed: %v", err)
+			}
+
+			// Second holder must be refused
+			second := NewLockInfo("plan", "", time.Hour)
+			err := backend.Lock(second)
+			var locked *LockedError
+			if !errors.As(err, &locked) {
+				t.Fatalf("expected LockedError, got %v", err)
+			}
+			if locked.Info.ID != first.ID {
+				t.Errorf("expected holder '%s', got '%s'", first.ID, locked.Info.ID)
+			}
+
+			// Unlock with the wrong ID must fail
+			if err := backend.Unlock(second.ID); err == nil {
+				t.Error("expected error when unlocking with a foreign ID")
+			}
+
+			if err := backend.Unlock(first.ID); err != nil {
+				t.Fatalf("Unlock failed: %v", err)
+			}
+			info, err := backend.Info()
+			if err != nil {
+				t.Fatalf("Info failed: %v", err)
+			}
+			if info != nil {
+				t.Errorf("expected state to be unlocked, got %v", info)
+			}
+
+			if err := backend.Lock(second); err != nil {
+				t.Fatalf("Lock after unlock failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestBackend_ExpiredLockIsTakenOver(t *testing.T) {
+	for name, backend := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			stale := NewLockInfo("apply", "", time.Hour)
+			stale.Expires = time.Now().Add(-time.Minute)
+			if err := backend.Lock(stale); err != nil {
+				t.Fatalf("Lock failed: %v", err)
+			}
+
+			fresh := NewLockInfo("apply", "", time.Hour)
+			if err := backend.Lock(fresh); err != nil {
+				t.Fatalf("expected expired lock to be taken over, got %v", err)
+			}
+
+			current, err := backend.Info()
+			if err != nil {
+				t.Fatalf("Info failed: %v", err)
+			}
+			if current == nil || current.ID != fresh.ID {
+				t.Errorf("expected lock '%s', got %v", fresh.ID, current)
+			}
+		})
+	}
+}
+
+func TestLocalBackend_ConcurrentTakeover(t *testing.T) {
+	backend := NewLocalBackend(filepath.Join(t.TempDir(), "state.lock"))
+	stale := NewLockInfo("apply", "", time.Hour)
+	stale.Expires = time.Now().Add(-time.Minute)
+	if err := backend.Lock(stale); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	infos := make([]LockInfo, 8)
+	errs := make([]error, len(infos))
+	var wg sync.WaitGroup
+	for i := range infos {
+		infos[i] = NewLockInfo("apply", "", time.Hour)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = backend.Lock(infos[i])
+		}()
+	}
+	wg.Wait()
+
+	current, err := backend.Info()
+	if err != nil || current == nil {
+		t.Fatalf("expected a lock, got %v (%v)", current, err)
+	}
+	acquired := 0
+	for i, info := range infos {
+		if errs[i] == nil {
+			acquired++
+		}
+		if info.ID == current.ID && errs[i] != nil {
+			t.Errorf("expected the holder of the lock file to succeed, got %v", errs[i])
+		}
+	}
+	if acquired != 1 {
+		t.Errorf("expected exactly one run to take over the lock, got %d", acquired)
+	}
+}
+
+func TestLocalBackend_TakeoverMarker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.lock")
+	backend := NewLocalBackend(path)
+	stale := NewLockInfo("apply", "", time.Hour)
+	stale.Expires = time.Now().Add(-time.Minute)
+	if err := backend.Lock(stale); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	// A marker left behind by a killed takeover blocks further takeovers
+	if err := os.WriteFile(path+".takeover", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Lock(NewLockInfo("apply", "", time.Hour)); err == nil {
+		t.Fatal("expected takeover to fail while the marker exists")
+	}
+
+	if err := backend.ForceUnlock(); err != nil {
+		t.Fatalf("ForceUnlock failed: %v", err)
+	}
+	if _, err := os.Stat(path + ".takeover"); !os.IsNotExist(err) {
+		t.Errorf("expected ForceUnlock to remove the takeover marker, got %v", err)
+	}
+	if err := backend.Lock(NewLockInfo("apply", "", time.Hour)); err != nil {
+		t.Errorf("expected lock after ForceUnlock, got %v", err)
+	}
+}
+
+func TestBackend_ForceUnlock(t *testing.T) {
+	for name, backend := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := backend.Lock(NewLockInfo("apply", "", time.Hour)); err != nil {
+				t.Fatalf("Lock failed: %v", err)
+			}
+			if err := backend.ForceUnlock(); err != nil {
+				t.Fatalf("ForceUnlock failed: %v", err)
+			}
+			if err := backend.Lock(NewLockInfo("plan", "", time.Hour)); err != nil {
+				t.Fatalf("Lock after force-unlock failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	root := t.TempDir()
+
+	backend, err := New(config.StateLock{}, root, "acme", "staging")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	local, ok := backend.(*LocalBackend)
+	if !ok {
+		t.Fatalf("expected local backend, got %T", backend)
+	}
+	if local.path != filepath.Join(root, ".bear", "state.staging.lock") {
+		t.Errorf("unexpected lock path '%s'", local.path)
+	}
+
+	backend, err = New(config.StateLock{Backend: "http", URL: "https://locks.example.com/"}, root, "acme", "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if remote := backend.(*HTTPBackend); remote.url != "https://locks.example.com/acme/default" {
+		t.Errorf("unexpected lock url '%s'", remote.url)
+	}
+
+	if backend, err := New(config.StateLock{Backend: "none"}, root, "acme", ""); err != nil || backend != nil {
+		t.Errorf("expected no backend for 'none', got %v, %v", backend, err)
+	}
+	if _, err := New(config.StateLock{Backend: "http"}, root, "acme", ""); err == nil {
+		t.Error("expected error for http backend without url")
+	}
+	if _, err := New(config.StateLock{Backend: "s3"}, root, "acme", ""); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
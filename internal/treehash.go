@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// treeObject is an entry of 'git ls-tree'
+type treeObject struct {
+	Type string // "tree" or "blob"
+	Hash string
+}
+
+// getTreeObjects lists every tree and blob below rootPath at HEAD, keyed
+// by their workspace-relative path. The hashes are the object names that
+// 'git rev-parse HEAD:<path>' reports.
+func getTreeObjects(rootPath string) (map[string]treeObject, error) {
+	return listTree(rootPath, "HEAD")
+}
+
+// getWorktreeObjects lists the blobs of the working tree, including
+// uncommitted and untracked (not ignored) files, keyed by their
+// workspace-relative path. Staged blobs come from the index, files that
+// differ from it are hashed without writing them to the object database.
+// Trees are not listed, since the working tree has none.
+func getWorktreeObjects(rootPath string) (map[string]treeObject, error) {
+	cmd := exec.Command("git", "ls-files", "-s", "-z")
+	cmd.Dir = rootPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files failed: %w", err)
+	}
+	objects := parseLsFiles(string(output))
+
+	cmd = exec.Command("git", "ls-files", "-z", "--modified", "--deleted", "--others", "--exclude-standard")
+	cmd.Dir = rootPath
+	output, err = cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files failed: %w", err)
+	}
+
+	var paths []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(string(output), "\x00") {
+		// Deleted files are also listed as modified. Untracked
+		// repositories are listed as directories with a trailing slash.
+		if name == "" || seen[name] || strings.HasSuffix(name, "/") {
+			continue
+		}
+		seen[name] = true
+		if _, err := os.Lstat(filepath.Join(rootPath, name)); err != nil || strings.Contains(name, "\n") {
+			delete(objects, name)
+			continue
+		}
+		paths = append(paths, name)
+	}
+
+	if len(paths) > 0 {
+		// Without -w the blobs are only hashed, not written
+		hash := exec.Command("git", "hash-object", "--stdin-paths")
+		hash.Dir = rootPath
+		hash.Stdin = strings.NewReader(strings.Join(paths, "\n") + "\n")
+		output, err = hash.Output()
+		if err != nil {
+			return nil, fmt.Errorf("git hash-object failed: %w", err)
+		}
+		hashes := strings.Fields(string(output))
+		if len(hashes) != len(paths) {
+			return nil, fmt.Errorf("git hash-object returned %d hashes for %d files", len(hashes), len(paths))
+		}
+		for i, name := range paths {
+			objects[name] = treeObject{Type: "blob", Hash: hashes[i]}
+		}
+	}
+
+	// Bear's own state in .bear changes with every run
+	for name := range objects {
+		if name == ".bear" || strings.HasPrefix(name, ".bear/") {
+			delete(objects, name)
+		}
+	}
+
+	return objects, nil
+}
+
+// listTree lists every tree and blob below rootPath in a tree-ish
+func listTree(rootPath, treeish string) (map[string]treeObject, error) {
+	cmd := exec.Command("git", "ls-tree", "-r", "-t", "-z", treeish)
+	cmd.Dir = rootPath
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-tree failed: %w", err)
+	}
+
+	return parseLsTree(string(output)), nil
+}
+
+// parseLsTree parses the output of 'git ls-tree -z'. Records look like
+// "<mode> <type> <hash>\t<path>" and are NUL-separated.
+func parseLsTree(output string) map[string]treeObject {
+	objects := make(map[string]treeObject)
+
+	for _, record := range strings.Split(output, "\x00") {
+		meta, name, ok := strings.Cut(record, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 3 {
+			continue
+		}
+		objects[strings.TrimSuffix(name, "/")] = treeObject{Type: fields[1], Hash: fields[2]}
+	}
+
+	return objects
+}
+
+// parseLsFiles parses the output of 'git ls-files -s -z'. Records look like
+// "<mode> <hash> <stage>\t<path>" and are NUL-separated.
+func parseLsFiles(output string) map[string]treeObject {
+	objects := make(map[string]treeObject)
+
+	for _, record := range strings.Split(output, "\x00") {
+		meta, name, ok := strings.Cut(record, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 3 {
+			continue
+		}
+		objects[name] = treeObject{Type: "blob", Hash: fields[1]}
+	}
+
+	return objects
+}
+
+// ContentHashes returns the content hash of every artifact in the working
+// tree, including uncommitted changes. Artifacts without files get no hash.
+// The working tree has no git trees, so every hash covers the files.
+//
+// The hash covers ignored files as well: ignore only keeps files from
+// triggering a deploy, validation steps still read them.
+func ContentHashes(rootPath string, artifacts []DiscoveredArtifact) (map[string]string, error) {
+	objects, err := getWorktreeObjects(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[string]string, len(artifacts))
+	for _, artifact := range artifacts {
+		relPath, _ := filepath.Rel(rootPath, artifact.Path)
+		rules := newChangeRules(relPath, artifact.Artifact)
+		rules.ignore = nil
+		if h := treeHash(rules, objects); h != "" {
+			hashes[artifact.Artifact.Name] = h
+		}
+	}
+	return hashes, nil
+}
+
+// treeHash returns the content hash of an artifact in the listed tree.
+//
+// An artifact without watch or ignore rules is identified by the git tree
+// hash of its directory. Otherwise the hash covers every file matched by
+// the rules, so watched paths count and ignored files do not.
+func treeHash(rules changeRules, objects map[string]treeObject) string {
+	if len(rules.watch) == 0 && len(rules.ignore) == 0 {
+		if obj, ok := objects[rules.path]; ok && obj.Type == "tree" {
+			return obj.Hash
+		}
+	}
+
+	var lines []string
+	for name, obj := range objects {
+		if obj.Type != "blob" {
+			continue
+		}
+		if rules.match(name) != "" {
+			lines = append(lines, obj.Hash+" "+name)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	sort.Strings(lines)
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
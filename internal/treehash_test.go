@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/irevolve/bear/internal/config"
+)
+
+func TestParseLsTree(t *testing.T) {
+	output := "040000 tree aaa\tservices\x00" +
+		"040000 tree bbb\tservices/api\x00" +
+		"100644 blob ccc\tservices/api/main go.go\x00" +
+		"040000 tree ddd\t./\x00"
+
+	objects := parseLsTree(output)
+
+	expected := map[string]treeObject{
+		"services":                {Type: "tree", Hash: "aaa"},
+		"services/api":            {Type: "tree", Hash: "bbb"},
+		"services/api/main go.go": {Type: "blob", Hash: "ccc"},
+		".":                       {Type: "tree", Hash: "ddd"},
+	}
+	if len(objects) != len(expected) {
+		t.Fatalf("expected %d objects, got %v", len(expected), objects)
+	}
+	for name, obj := range expected {
+		if objects[name] != obj {
+			t.Errorf("object '%s': expected %v, got %v", name, obj, objects[name])
+		}
+	}
+}
+
+func TestParseLsFiles(t *testing.T) {
+	output := "100644 aaa 0\tservices/api/main go.go\x00" +
+		"100755 bbb 0\tservices/api/run.sh\x00"
+
+	objects := parseLsFiles(output)
+
+	expected := map[string]treeObject{
+		"services/api/main go.go": {Type: "blob", Hash: "aaa"},
+		"services/api/run.sh":     {Type: "blob", Hash: "bbb"},
+	}
+	if len(objects) != len(expected) {
+		t.Fatalf("expected %d objects, got %v", len(expected), objects)
+	}
+	for name, obj := range expected {
+		if objects[name] != obj {
+			t.Errorf("object '%s': expected %v, got %v", name, obj, objects[name])
+		}
+	}
+}
+
+func TestTreeHash(t *testing.T) {
+	objects := map[string]treeObject{
+		"services/api":           {Type: "tree", Hash: "api-tree"},
+		"services/api/main.go":   {Type: "blob", Hash: "main-v1"},
+		"services/api/README.md": {Type: "blob", Hash: "readme-v1"},
+		"proto":                  {Type: "tree", Hash: "proto-tree"},
+		"proto/user.proto":       {Type: "blob", Hash: "proto-v1"},
+	}
+
+	// Plain artifacts use the git tree hash of their directory
+	if got := treeHash(changeRules{path: "services/api"}, objects); got != "api-tree" {
+		t.Errorf("expected 'api-tree', got '%s'", got)
+	}
+	if got := treeHash(changeRules{path: "services/web"}, objects); got != "" {
+		t.Errorf("expected no hash for missing directory, got '%s'", got)
+	}
+
+	rules := changeRules{path: "services/api", watch: []string{"../../proto"}, ignore: []string{"*.md"}}
+	base := treeHash(rules, objects)
+	if base == "" || base == "api-tree" {
+		t.Fatalf("expected a combined hash, got '%s'", base)
+	}
+
+	// Ignored files do not change the hash
+	objects["services/api/README.md"] = treeObject{Type: "blob", Hash: "readme-v2"}
+	if got := treeHash(rules, objects); got != base {
+		t.Errorf("expected ignored change to keep hash '%s', got '%s'", base, got)
+	}
+
+	// Watched files do
+	objects["proto/user.proto"] = treeObject{Type: "blob", Hash: "proto-v2"}
+	if got := treeHash(rules, objects); got == base {
+		t.Error("expected watched change to change the hash")
+	}
+}
+
+func TestContentHashes_IgnoredFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("requires git")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v: %s", err, out)
+	}
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	write("api/main.go", "package main")
+	write("api/main_test.go", "package main")
+
+	artifacts := []DiscoveredArtifact{{
+		Path:     filepath.Join(dir, "api"),
+		Artifact: &config.Artifact{Name: "api", Ignore: []string{"**/*_test.go"}},
+	}}
+	before, err := ContentHashes(dir, artifacts)
+	if err != nil {
+		t.Fatalf("ContentHashes failed: %v", err)
+	}
+
+	// Validation runs the tests, so an ignored test file changes the hash
+	write("api/main_test.go", "package main // broken")
+	after, err := ContentHashes(dir, artifacts)
+	if err != nil {
+		t.Fatalf("ContentHashes failed: %v", err)
+	}
+	if before["api"] == "" || before["api"] == after["api"] {
+		t.Errorf("expected the hash to change with an ignored file, got '%s' and '%s'", before["api"], after["api"])
+	}
+}